@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dariodip/prefix-search/prefix-search/stringcoding"
+)
+
+// Names of the integer codes that can be used for Lengths
+const (
+	gammaCoderConst  = "gamma"
+	deltaCoderConst  = "delta"
+	riceCoderConst   = "rice"
+	fixedCoderConst  = "fixed"
+	nibbleCoderConst = "nibble"
+)
+
+// Returns the integer code having the given name
+func getLengthsCoder(name string) (stringcoding.IntCoder, error) {
+	switch name {
+	case gammaCoderConst:
+		return stringcoding.EliasGammaCoder{}, nil
+	case deltaCoderConst:
+		return stringcoding.EliasDeltaCoder{}, nil
+	case riceCoderConst:
+		return stringcoding.AutoRiceCoder(), nil
+	case fixedCoderConst:
+		return stringcoding.AutoFixedWidthCoder(), nil
+	case nibbleCoderConst:
+		return stringcoding.NibbleCoder{}, nil
+	}
+	return nil, fmt.Errorf(`insert a coder between "gamma", "delta", "rice", "fixed" and "nibble"`)
+}
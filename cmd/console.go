@@ -4,11 +4,13 @@ import (
 	"fmt"
 
 	"bufio"
+	"context"
 	"github.com/dariodip/prefix-search/prefix-search/stringcoding"
 	"github.com/dariodip/prefix-search/word-reader"
 	"github.com/spf13/cobra"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 )
 
@@ -19,7 +21,8 @@ var consoleCmd = &cobra.Command{
 	Use:   "console",
 	Short: "Start interactive console",
 	Long: `Using "console" you can start interactive console that gives you the opportunity
-to, given a preloaded dataset, to find prefixes interactively.`,
+to, given a preloaded dataset, to find prefixes interactively.
+Ctrl-C stops the search in progress, or ends the console if no search is in progress.`,
 	Run: runConsole,
 }
 
@@ -28,16 +31,22 @@ func init() {
 
 	consoleCmd.Flags().StringVarP(&inputFile, "input_file", "i", "", "Input file containing"+
 		" all the word to build up the dictionary.")
-	consoleCmd.MarkFlagRequired("input_file")
 	consoleCmd.MarkFlagFilename("input_file")
 
+	consoleCmd.Flags().StringVarP(&indexFile, "index_file", "x", "", "Index file containing"+
+		" a dictionary already built. If the file does not exist, the dictionary built from the input file"+
+		" is saved into it.")
+	consoleCmd.MarkFlagFilename("index_file")
+
 	consoleCmd.Flags().StringVarP(&algorithm, "algorithm", "a", "lprc", "Algorithm"+
 		"to use")
 	consoleCmd.MarkFlagRequired("algorithm")
 
 	consoleCmd.Flags().Float64VarP(&epsilon, "epsilon", "e", 0, "Epsilon is the parameter"+
 		"given to the algorithm in order to decide how many bits compress in the trie.")
-	consoleCmd.MarkFlagRequired("epsilon")
+
+	consoleCmd.Flags().BoolVarP(&multiset, "multiset", "m", false, "Keep the empty lines and the"+
+		" duplicated words of the input file, instead of refusing to build the dictionary.")
 
 }
 
@@ -47,38 +56,69 @@ func runConsole(cmd *cobra.Command, args []string) {
 	fmt.Println("Enter a prefix to search: ")
 
 	var (
-		wr   = wordreader.New(inputFile)
 		impl stringcoding.PrefixSearch
+		err  error
 	)
 
 	startTime := time.Now()
-	lines, err := wr.ReadLines() // read all lines from the file
-	if err != nil {
-		err = fmt.Errorf("error in load lines from file: %s", err)
-		fmt.Println(err)
-		os.Exit(1)
-	}
-
-	if algorithm == LPRCconst {
-		lprcImpl := stringcoding.NewLPRC(wr.Strings, epsilon)
-		impl = &lprcImpl
-	} else if algorithm == PSRCconst {
-		psrcImpl := stringcoding.NewPSRC(wr.Strings, epsilon)
-		impl = &psrcImpl
+	if indexFile != "" && fileExists(indexFile) { // the dictionary has already been built
+		impl, err = loadIndex(indexFile, algorithm)
+		if err != nil {
+			fmt.Println(fmt.Errorf("error in load index from file: %s", err))
+			os.Exit(1)
+		}
+		fmt.Printf("Loaded index %s in %v \n", indexFile, time.Since(startTime))
 	} else {
-		err = fmt.Errorf(`insert an algorithm between "lprc" and "psrc" \n`)
-		fmt.Println(err)
-		os.Exit(1)
-	}
+		if inputFile == "" || epsilon <= 0 {
+			fmt.Println(fmt.Errorf("an input file and an epsilon greater than 0 are required to build the dictionary"))
+			os.Exit(1)
+		}
+		wr := wordreader.New(inputFile)
+		lines, err := wr.ReadLines() // read all lines from the file
+		if err != nil {
+			err = fmt.Errorf("error in load lines from file: %s", err)
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-	if err := impl.Populate(); err != nil {
-		fmt.Println(err)
+		if algorithm == LPRCconst {
+			lprcImpl, err := stringcoding.NewLPRC(wr.Strings, epsilon, stringcoding.WithMultiset(multiset))
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			impl = &lprcImpl
+		} else if algorithm == PSRCconst {
+			psrcImpl, err := stringcoding.NewPSRC(wr.Strings, epsilon, stringcoding.WithMultiset(multiset))
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			impl = &psrcImpl
+		} else {
+			err = fmt.Errorf(`insert an algorithm between "lprc" and "psrc" \n`)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := impl.Populate(); err != nil {
+			fmt.Println(fmt.Errorf("error in build the dictionary: %s (use --multiset to keep empty"+
+				" lines and duplicated words)", err))
+			os.Exit(1)
+		}
+		fmt.Printf("Loaded %d words in %v \n", lines, time.Since(startTime))
+
+		if indexFile != "" {
+			if err := saveIndex(indexFile, impl); err != nil {
+				fmt.Println(fmt.Errorf("error in save index to file: %s", err))
+			}
+		}
 	}
-	fmt.Printf("Loaded %d words in %v \n", lines, time.Since(startTime))
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
-	go handleInterrupt(c)
+	interrupt := &interruptHandler{}
+	go interrupt.handle(c)
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Print(consoleMarker)
@@ -91,19 +131,19 @@ func runConsole(cmd *cobra.Command, args []string) {
 		fmt.Println("Searching for strings starting with ", prefix)
 
 		startTime = time.Now()
-		strings, err := impl.FullPrefixSearch(prefix)
-		if err != nil {
-			fmt.Println(fmt.Errorf("error: %s", err))
-			fmt.Print(consoleMarker)
-			continue
-		}
-		fmt.Printf("Found %d strings in %v \n", len(strings), time.Since(startTime))
-		if len(strings) == 0 {
-			fmt.Println("No string found")
-		}
-		for i, s := range strings {
+		ctx := interrupt.startQuery()
+		it := impl.PrefixIteratorContext(ctx, prefix) // strings are decoded while they are shown
+		found := 0
+		for {
+			s, ok := it.Next()
+			if !ok {
+				break
+			}
+			if found == 0 {
+				fmt.Printf("Found the first string in %v \n", time.Since(startTime))
+			}
 			endPrint := false
-			if i%10 == 0 && i > 0 {
+			if found%10 == 0 && found > 0 {
 				usage := "[...] hit Enter to continue or q + Enter to end the visualization"
 				fmt.Println(usage)
 				for {
@@ -122,16 +162,60 @@ func runConsole(cmd *cobra.Command, args []string) {
 			if endPrint {
 				break
 			}
-			fmt.Printf("%d) %s \n", i+1, s)
+			found++
+			fmt.Printf("%d) %s \n", found, s)
+		}
+		interrupt.endQuery()
+		if err := it.Err(); err == context.Canceled {
+			fmt.Println("Search interrupted")
+		} else if err != nil {
+			fmt.Println(fmt.Errorf("error: %s", err))
+		} else if found == 0 {
+			fmt.Println("No string found")
 		}
 		fmt.Print(consoleMarker)
 	}
 
 }
 
-func handleInterrupt(c chan os.Signal) {
-	<-c
-	fmt.Printf("Received interrupt signal \n")
-	fmt.Println("Bye")
-	os.Exit(0)
+// interruptHandler cancels the search in progress when an interrupt signal is received,
+// while it ends the console if no search is in progress.
+type interruptHandler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// startQuery returns the context of a new search, canceled by the next interrupt signal.
+func (h *interruptHandler) startQuery() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cancel = cancel
+	return ctx
+}
+
+// endQuery tells that the search started by startQuery is over.
+func (h *interruptHandler) endQuery() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+func (h *interruptHandler) handle(c chan os.Signal) {
+	for range c {
+		h.mu.Lock()
+		cancel := h.cancel
+		h.mu.Unlock()
+		if cancel != nil { // only the search is interrupted
+			fmt.Printf("Received interrupt signal, stopping the search \n")
+			cancel()
+			continue
+		}
+		fmt.Printf("Received interrupt signal \n")
+		fmt.Println("Bye")
+		os.Exit(0)
+	}
 }
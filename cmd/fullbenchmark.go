@@ -44,10 +44,22 @@ func init() {
 
 	fullbenchmarkCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Detailed Output ")
 
+	fullbenchmarkCmd.Flags().BoolVarP(&countOnly, "count_only", "k", false, "Measure the time needed to count"+
+		" the strings starting with each prefix, without retrieving them.")
+
+	fullbenchmarkCmd.Flags().StringVarP(&coderName, "coder", "c", gammaCoderConst, "Integer code used to"+
+		" write the lengths: gamma, delta, rice, fixed or nibble.")
+
+	fullbenchmarkCmd.Flags().IntVarP(&workers, "workers", "w", 1, "Number of goroutines coding the"+
+		" dictionary of lprc: 0 uses a goroutine for each CPU.")
+
 	fullbenchmarkCmd.Flags().StringVarP(&algorithm, "algorithm", "a", "lprc", "Algorithm"+
 		"to use")
 	fullbenchmarkCmd.MarkFlagRequired("algorithm")
 
+	fullbenchmarkCmd.Flags().BoolVarP(&eliasFano, "elias_fano", "f", false, "Represent the starts of the"+
+		" strings with Elias-Fano instead of a bitvector, in order to save space.")
+
 	fullbenchmarkCmd.Flags().StringVarP(&outputFile, "output_file", "o", "", "Output file"+
 		" containing the final output of lprc, with information about the memory usage and the time elapsed.\n"+
 		"Default <algorithm>-<word filename>-<prefix file name>-<min_epsilon>-<max_epsilon>.json")
@@ -82,12 +94,18 @@ func fullBenchmark() {
 		epsilonListFloat = append(epsilonListFloat, eFloat)
 	}
 
+	coder, err := getLengthsCoder(coderName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	for _, eps := range epsilonListFloat {
 		var impl stringcoding.PrefixSearch
 		var initTime time.Duration
 
 		if algorithm == LPRCconst {
-			lprcImpl, iTime, err := initLPRC(wr.Strings, eps)
+			lprcImpl, iTime, err := initLPRC(wr.Strings, eps, coder, workers, eliasFano)
 			if err != nil {
 				fmt.Printf("Unable to complete the benchmark: %s\n", err)
 				os.Exit(-1)
@@ -95,7 +113,7 @@ func fullBenchmark() {
 			impl = lprcImpl
 			initTime = iTime
 		} else if algorithm == PSRCconst {
-			psrcImpl, iTime, err := initPSRC(wr.Strings, eps)
+			psrcImpl, iTime, err := initPSRC(wr.Strings, eps, coder, eliasFano)
 			if err != nil {
 				fmt.Printf("Unable to complete the benchmark: %s\n", err)
 				os.Exit(-1)
@@ -112,37 +130,46 @@ func fullBenchmark() {
 		totalBitSize := totalSize(bdSize)
 		fmt.Printf("Initialization time:   %v\n", initTime)
 		fmt.Printf("Size of the structure: %d bits\n", totalBitSize)
+		fmt.Printf("Lengths coder:         %s\n", impl.LengthsCoderName())
+		fmt.Printf("Starts encoding:       %s\n", impl.StartsEncoding())
 		fmt.Println()
 
 		finalResults := &Result{
 			InitTime:             toMilliseconds(initTime),
 			Epsilon:              eps,
 			StructureSize:        bdSize,
+			LengthsCoder:         impl.LengthsCoderName(),
+			StartsEncoding:       impl.StartsEncoding(),
 			UncompressedDataSize: getBitSize(wr.Strings),
+			CountOnly:            countOnly,
 		}
 
 		var searchTime time.Time
 		var elapsedTime time.Duration
 		totalSearchTime := time.Duration(0)
 		updateResult := updateResultTemplate(verbose, len(wrp.Strings))
+		searchName := "Full-Prefix-Search"
+		if countOnly {
+			searchName = "Count-Prefix"
+		}
 		for _, prefix := range wrp.Strings {
 			searchTime = time.Now()
-			result, err := impl.FullPrefixSearch(prefix)
+			found, err := searchPrefix(impl, prefix)
 			if err != nil {
 				fmt.Printf("error: %s\n", err)
 				continue
 			}
 			elapsedTime = time.Since(searchTime)
 
-			updateResult(fmt.Sprintf("Full-Prefix-Search for prefix %s -> strings found: %d, time elapsed: %v\n",
-				prefix, len(result), elapsedTime))
+			updateResult(fmt.Sprintf("%s for prefix %s -> strings found: %d, time elapsed: %v\n",
+				searchName, prefix, found, elapsedTime))
 
 			totalSearchTime += elapsedTime
-			finalResults.addResultRow(prefix, len(result), elapsedTime)
+			finalResults.addResultRow(prefix, found, elapsedTime)
 		}
 
 		fmt.Println()
-		fmt.Printf("Full-Prefix-Search total elapsed time: %v\n", totalSearchTime)
+		fmt.Printf("%s total elapsed time: %v\n", searchName, totalSearchTime)
 
 		finalResults.TotalSearchTime = toMilliseconds(totalSearchTime)
 
@@ -151,6 +178,17 @@ func fullBenchmark() {
 	saveAllToFile(allResults, outputFile)
 }
 
+// searchPrefix returns the number of strings starting with prefix: if countOnly is set
+// they are only counted, otherwise they are retrieved with FullPrefixSearch.
+func searchPrefix(impl stringcoding.PrefixSearch, prefix string) (int, error) {
+	if countOnly {
+		count, err := impl.CountPrefix(prefix)
+		return int(count), err
+	}
+	result, err := impl.FullPrefixSearch(prefix)
+	return len(result), err
+}
+
 func totalSize(bdSize map[string]uint64) uint64 {
 	var totalBitSize uint64
 	for _, size := range bdSize {
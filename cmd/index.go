@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/dariodip/prefix-search/prefix-search/stringcoding"
+)
+
+// Loads from a file a structure built with the given algorithm
+func loadIndex(path string, algorithm string) (stringcoding.PrefixSearch, error) {
+	var impl stringcoding.PrefixSearch
+	if algorithm == LPRCconst {
+		impl = &stringcoding.LPRC{}
+	} else if algorithm == PSRCconst {
+		impl = &stringcoding.PSRC{}
+	} else {
+		return nil, fmt.Errorf(`insert an algorithm between "lprc" and "psrc"`)
+	}
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	if _, err := impl.ReadFrom(bufio.NewReader(fp)); err != nil {
+		return nil, err
+	}
+	return impl, nil
+}
+
+// Saves to a file a populated structure
+func saveIndex(path string, impl stringcoding.PrefixSearch) error {
+	fp, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	w := bufio.NewWriter(fp)
+	if _, err := impl.WriteTo(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Returns true if the file exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -57,6 +57,15 @@ func init() {
 
 	lprcCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Detailed Output ")
 
+	lprcCmd.Flags().StringVarP(&coderName, "coder", "c", gammaCoderConst, "Integer code used to"+
+		" write the lengths: gamma, delta, rice, fixed or nibble.")
+
+	lprcCmd.Flags().IntVarP(&workers, "workers", "w", 1, "Number of goroutines coding the"+
+		" dictionary of lprc: 0 uses a goroutine for each CPU.")
+
+	lprcCmd.Flags().BoolVarP(&eliasFano, "elias_fano", "f", false, "Represent the starts of the"+
+		" strings with Elias-Fano instead of a bitvector, in order to save space.")
+
 	lprcCmd.Flags().StringVarP(&outputFile, "output_file", "o", "", "Output file"+
 		" containing the final output of lprc, with information about the memory usage and the time elapsed.\n"+
 		"Default <word filename>-<prefix file name>-<epsilon>.json")
@@ -72,16 +81,24 @@ func lprcBenchmark() {
 	wrp := wordreader.New(inputPrefixFile)
 	wrp.ReadLines()
 
-	lprcImpl, initTime, err := initLPRC(wr.Strings, epsilon)
+	coder, err := getLengthsCoder(coderName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	lprcImpl, initTime, err := initLPRC(wr.Strings, epsilon, coder, workers, eliasFano)
 	if err != nil {
 		fmt.Printf("Unable to complete the benchmark: %s\n", err)
 		os.Exit(-1)
 	}
 
 	bdSize := lprcImpl.GetBitDataSize()
-	totalBitSize := bdSize["StringsSize"] + bdSize["StartsSize"] + bdSize["LengthsSize"] + bdSize["IsUncompressedSize"]
+	totalBitSize := totalSize(bdSize)
 	fmt.Printf("Initialization time:   %v\n", initTime)
 	fmt.Printf("Size of the structure: %d bits\n", totalBitSize)
+	fmt.Printf("Lengths coder:         %s\n", lprcImpl.LengthsCoderName())
+	fmt.Printf("Starts encoding:       %s\n", lprcImpl.StartsEncoding())
 	fmt.Println()
 
 	if outputFile == "" { // no output file specified
@@ -92,6 +109,8 @@ func lprcBenchmark() {
 		InitTime:             toMilliseconds(initTime),
 		Epsilon:              lprcImpl.Epsilon,
 		StructureSize:        bdSize,
+		LengthsCoder:         lprcImpl.LengthsCoderName(),
+		StartsEncoding:       lprcImpl.StartsEncoding(),
 		UncompressedDataSize: getBitSize(wr.Strings),
 	}
 	defer saveToFile(finalResults, outputFile)
@@ -122,10 +141,15 @@ func lprcBenchmark() {
 	finalResults.TotalSearchTime = toMilliseconds(totalSearchTime)
 }
 
-func initLPRC(strings []string, epsilon float64) (*stringcoding.LPRC, time.Duration, error) {
+func initLPRC(strings []string, epsilon float64, coder stringcoding.IntCoder, workers int,
+	eliasFano bool) (*stringcoding.LPRC, time.Duration, error) {
 	startTime := time.Now()
-	lprcImpl := stringcoding.NewLPRC(strings, epsilon)
-	if err := lprcImpl.Populate(); err != nil {
+	lprcImpl, err := stringcoding.NewLPRC(strings, epsilon, stringcoding.WithLengthsCoder(coder),
+		stringcoding.WithEliasFanoStarts(eliasFano))
+	if err != nil {
+		return nil, time.Duration(0), err
+	}
+	if err := lprcImpl.PopulateParallel(workers); err != nil {
 		return nil, time.Duration(0), err
 	}
 
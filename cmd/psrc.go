@@ -47,6 +47,12 @@ func init() {
 
 	psrcCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Detailed Output ")
 
+	psrcCmd.Flags().StringVarP(&coderName, "coder", "c", gammaCoderConst, "Integer code used to"+
+		" write the lengths: gamma, delta, rice, fixed or nibble.")
+
+	psrcCmd.Flags().BoolVarP(&eliasFano, "elias_fano", "f", false, "Represent the starts of the"+
+		" strings with Elias-Fano instead of a bitvector, in order to save space.")
+
 	psrcCmd.Flags().StringVarP(&outputFile, "output_file", "o", "", "Output file"+
 		" containing the final output of lprc, with information about the memory usage and the time elapsed.\n"+
 		"Default <word filename>-<prefix file name>-<epsilon>.json")
@@ -62,16 +68,24 @@ func psrcBenchmark() {
 	wrp := wordreader.New(inputPrefixFile)
 	wrp.ReadLines()
 
-	psrcImpl, initTime, err := initPSRC(wr.Strings, epsilon)
+	coder, err := getLengthsCoder(coderName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	psrcImpl, initTime, err := initPSRC(wr.Strings, epsilon, coder, eliasFano)
 	if err != nil {
 		fmt.Printf("Unable to complete the benchmark: %s\n", err)
 		os.Exit(-1)
 	}
 
 	bdSize := psrcImpl.GetBitDataSize()
-	totalBitSize := bdSize["StringsSize"] + bdSize["StartsSize"] + bdSize["LengthsSize"] + bdSize["IsUncompressedSize"] + bdSize["PrefixOrSuffixSize"]
+	totalBitSize := totalSize(bdSize)
 	fmt.Printf("Initialization time:   %v\n", initTime)
 	fmt.Printf("Size of the structure: %d bits\n", totalBitSize)
+	fmt.Printf("Lengths coder:         %s\n", psrcImpl.LengthsCoderName())
+	fmt.Printf("Starts encoding:       %s\n", psrcImpl.StartsEncoding())
 	fmt.Println()
 
 	if outputFile == "" { // no output file specified
@@ -82,6 +96,8 @@ func psrcBenchmark() {
 		InitTime:             toMilliseconds(initTime),
 		Epsilon:              psrcImpl.Epsilon,
 		StructureSize:        bdSize,
+		LengthsCoder:         psrcImpl.LengthsCoderName(),
+		StartsEncoding:       psrcImpl.StartsEncoding(),
 		UncompressedDataSize: getBitSize(wr.Strings),
 	}
 	defer saveToFile(finalResults, outputFile)
@@ -112,9 +128,14 @@ func psrcBenchmark() {
 	finalResults.TotalSearchTime = toMilliseconds(totalSearchTime)
 }
 
-func initPSRC(strings []string, epsilon float64) (*stringcoding.PSRC, time.Duration, error) {
+func initPSRC(strings []string, epsilon float64, coder stringcoding.IntCoder, eliasFano bool) (*stringcoding.PSRC,
+	time.Duration, error) {
 	startTime := time.Now()
-	psrcImpl := stringcoding.NewPSRC(strings, epsilon)
+	psrcImpl, err := stringcoding.NewPSRC(strings, epsilon, stringcoding.WithLengthsCoder(coder),
+		stringcoding.WithEliasFanoStarts(eliasFano))
+	if err != nil {
+		return nil, time.Duration(0), err
+	}
 	if err := psrcImpl.Populate(); err != nil {
 		return nil, time.Duration(0), err
 	}
@@ -15,11 +15,17 @@ var (
 	VERSION         string
 	inputFile       string
 	inputPrefixFile string
+	indexFile       string
 	outputFile      string
 	algorithm       string
+	coderName       string
 	epsilon         float64
 	epsilonList     []string
 	verbose         bool
+	countOnly       bool
+	multiset        bool
+	workers         int
+	eliasFano       bool
 	LPRCconst       = "lprc"
 	PSRCconst       = "psrc"
 )
@@ -36,9 +42,13 @@ type Result struct {
 	InitTime             float64
 	Epsilon              float64
 	StructureSize        map[string]uint64
+	LengthsCoder         string
+	StartsEncoding       string
 	UncompressedDataSize uint64
 	PrefixResult         []ResultRow
 	TotalSearchTime      float64
+	// CountOnly tells if the prefixes have been counted instead of searched
+	CountOnly bool
 }
 
 func (res *Result) addResultRow(prefix string, wordCount int, searchTime time.Duration) {
@@ -4,22 +4,29 @@ package bitdata
 import (
 	"bytes"
 	"fmt"
-	"github.com/dariodip/prefix-search/prefix-search/bititerator"
-	"github.com/golang-collections/go-datastructures/bitarray"
+	"math/bits"
+	"unsafe"
 )
 
 // BitData type abstracts a string accessible as a sequence of bits;
 // it also contains information about the number of bits.
 type BitData struct {
-	// List of bits representing some data.
-	bits bitarray.BitArray
-	// Number of significant bits in the BitArray.
+	// List of bits representing some data, packed in words of 64 bits:
+	// the bit in position i is the bit i%64 of words[i/64].
+	words []uint64
+	// Number of significant bits in words.
 	Len uint64
+	// Optional rank/select directory, see BuildIndex.
+	index *rankSelectIndex
 }
 
-// New returns a pointer to a new BitData structure of the specified size.
-func New(ba bitarray.BitArray, len uint64) *BitData {
-	return &BitData{ba, len}
+// New returns a pointer to a new BitData of len bits, all 0s, having room for
+// capacity bits (or len bits, if they are more) before it has to grow.
+func New(capacity uint64, len uint64) *BitData {
+	if capacity < len {
+		capacity = len
+	}
+	return &BitData{words: make([]uint64, wordsCount(capacity)), Len: len}
 }
 
 // GetBitData , given a string 's', returns a pointer to a BitData
@@ -27,17 +34,25 @@ func New(ba bitarray.BitArray, len uint64) *BitData {
 // a nil pointer and and error.
 func GetBitData(s string) (*BitData, error) {
 	var (
-		sBitLen = GetLengthInBit(s)                     // length in bit of the string
-		btdata  = New(bitarray.NewBitArray(sBitLen), 0) // empty BitData
-		bitit   = bititerator.NewStringToBitIterator(s) // BitIterator on the string s
+		sBitLen = GetLengthInBit(s) // length in bit of the string
+		btdata  = New(sBitLen, 0)   // empty BitData
 	)
-
-	for bitit.HasNext() {
-		bit, err := bitit.Next()
-		if err != nil {
-			panic(err)
+	// the last character is in the least significant bits, so the string
+	// is appended 8 characters at a time starting from the end
+	for end := len(s); end > 0; end -= 8 {
+		var (
+			start = end - 8
+			word  uint64
+		)
+		if start < 0 {
+			start = 0
+		}
+		for k := end - 1; k >= start; k-- {
+			word |= uint64(s[k]) << (8 * uint(end-1-k))
+		}
+		if err := btdata.AppendWord(word, uint64(8*(end-start))); err != nil {
+			return nil, err
 		}
-		btdata.AppendBit(bit)
 	}
 	return btdata, nil
 }
@@ -53,31 +68,27 @@ func (s1 *BitData) GetBit(index uint64) (bool, error) {
 	if index >= s1.Len {
 		return false, ErrIndexOutOfBound
 	}
-	return s1.bits.GetBit(index)
+	return s1.words[index/blockSize]>>(index%blockSize)&1 == 1, nil
 }
 
-// AppendBits appends the bits of a BitData (s2) onto the s1 BitData.
+// AppendBits appends the bits of a BitData (s2) onto the s1 BitData, a word at a time.
 func (s1 *BitData) AppendBits(s2 *BitData) error {
-	for i := uint64(0); i < s2.Len; i++ { // for each bit in the current string
-		bit, err := s2.GetBit(i) // get the i-th bit in s2
-		if err != nil {
-			return err
-		}
-		err = s1.AppendBit(bit) // then append it to s1
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return s1.AppendRange(s2, 0, s2.Len)
 }
 
-// AppendBit appends a bit given as a bool to the s1 BitData.
+// AppendBit appends a bit given as a bool to the s1 BitData, growing it if it is full.
 func (s1 *BitData) AppendBit(bit bool) error {
-	if bit { // if the bit to append is 1
-		err := s1.bits.SetBit(s1.Len) // append it to the next unmarked bit
-		if err != nil {
-			panic(err)
-		}
+	s1.Grow(1)
+	w, mask := s1.Len/blockSize, uint64(1)<<(s1.Len%blockSize)
+	if bit {
+		s1.words[w] |= mask
+	} else { // the storage after Len may be dirty
+		s1.words[w] &^= mask
+	}
+	if s1.HasIndex() { // keep the rank/select directory up to date
+		s1.index.append(bit)
+	} else {
+		s1.index = nil
 	}
 	s1.Len++ // always increment length
 	return nil
@@ -89,10 +100,8 @@ func (s1 *BitData) SetBit(index uint64) error {
 	if index >= s1.Len {
 		return ErrIndexOutOfBound
 	}
-	err := s1.bits.SetBit(index)
-	if err != nil {
-		return err
-	}
+	s1.words[index/blockSize] |= uint64(1) << (index % blockSize)
+	s1.index = nil // the rank/select directory is no longer valid
 	return nil
 }
 
@@ -102,10 +111,8 @@ func (s1 *BitData) ClearBit(index uint64) error {
 	if index >= s1.Len {
 		return ErrIndexOutOfBound
 	}
-	err := s1.bits.ClearBit(index)
-	if err != nil {
-		return err
-	}
+	s1.words[index/blockSize] &^= uint64(1) << (index % blockSize)
+	s1.index = nil // the rank/select directory is no longer valid
 	return nil
 }
 
@@ -113,115 +120,34 @@ func (s1 *BitData) ClearBit(index uint64) error {
 // BitData containing the suffix that is not equal between the two BitDatas.
 // If something goes wrong, returns a nil pointer and an error.
 func (s1 *BitData) GetDifferentSuffix(s2 *BitData) (*BitData, error) {
-	var (
-		commonPrefixLen uint64       // length of the common prefix
-		idx1            = s1.Len - 1 // last bit of the first "bitted" string
-		idx2            = s2.Len - 1 // last bit of the second "bitted" string
-	)
-	if s1.bits == nil {
+	if s1.words == nil {
 		return nil, ErrNotInitBitData
 	}
-	if s2.bits == nil || s2.Len == uint64(0) { // trying to find common prefix between a string and a void one
-		differentSuffix := New(bitarray.NewBitArray(s1.Len), 0)
-		for i := uint64(0); i < s1.Len; i++ { // we must copy the first array!
-			bit, errGet := s1.GetBit(i)
-			if errGet != nil {
-				return nil, errGet
-			}
-			errAppend := differentSuffix.AppendBit(bit)
-			if errAppend != nil {
-				return nil, errAppend
-			}
-		}
-		return differentSuffix, nil
+	if s2.words == nil || s2.Len == uint64(0) { // trying to find common prefix between a string and a void one
+		return s1.subData(0, s1.Len) // we must copy the first array!
 	}
-	// we must keep both the indexes in order to avoid out of bound
-	for {
-		bit1, e1 := s1.GetBit(idx1) // get bits in position idx1 (risp. idx2) on both strings
-		bit2, e2 := s2.GetBit(idx2)
-		if e1 != nil || e2 != nil { // something has gone wrong
-			return nil, &ErrInvalidPosition{idx1}
-		}
-		if bit1 == bit2 { // bits are still equal
-			commonPrefixLen++
-		} else {
-			break // bits are not still equal, we are in the different suffix
-		}
-		if idx1 == 0 || idx2 == 0 {
-			break // no more bit to scan
-		}
-		idx1--
-		idx2--
-	}
-
-	var (
-		suffixLen       = s2.Len - commonPrefixLen                // length of the different suffix
-		differentSuffix = New(bitarray.NewBitArray(suffixLen), 0) // init a new BitData to keep suffix
-	)
-	for i := uint64(0); i < suffixLen; i++ {
-		if bit, err := s2.GetBit(i); err == nil {
-			differentSuffix.AppendBit(bit)
-		} else {
-			return nil, err
-		}
+	commonPrefixLen, err := s1.CommonPrefixLen(s2) // length of the common prefix
+	if err != nil {
+		return nil, err
 	}
-	return differentSuffix, nil
+	return s2.subData(0, s2.Len-commonPrefixLen)
 }
 
 // GetDifferentPrefix ,given another pointer to a BitData, returns a new
 // BitData containing the prefix that is not equal between the two BitDatas.
 // If something goes wrong, returns a nil pointer and an error.
 func (s1 *BitData) GetDifferentPrefix(s2 *BitData) (*BitData, error) {
-	var (
-		commonSuffixLen uint64      // length of the common prefix
-		idx1            = uint64(0) // first bit of the first "bitted" string
-		idx2            = uint64(0) // first bit of the second "bitted" string
-	)
-	if s1.bits == nil {
+	if s1.words == nil {
 		return nil, ErrNotInitBitData
 	}
-	if s2.bits == nil || s2.Len == uint64(0) { // trying to find common suffix between a string and a void one
-		differentPrefix := New(bitarray.NewBitArray(s1.Len), 0)
-		for i := uint64(0); i < s1.Len; i++ { // we must copy the first array!
-			bit, errGet := s1.GetBit(i)
-			if errGet != nil {
-				return nil, errGet
-			}
-			errAppend := differentPrefix.AppendBit(bit)
-			if errAppend != nil {
-				return nil, errAppend
-			}
-		}
-		return s1, nil
-	}
-	// we must keep both the indexes in order to avoid out of bound
-	for idx1 != s1.Len && idx2 != s2.Len {
-		bit1, e1 := s1.GetBit(idx1) // get bits in position idx1 (risp. idx2) on both strings
-		bit2, e2 := s2.GetBit(idx2)
-		if e1 != nil || e2 != nil { // something has gone wrong
-			return nil, &ErrInvalidPosition{idx1}
-		}
-		if bit1 == bit2 { // bits are still equal
-			commonSuffixLen++
-		} else {
-			break // bits are not still equal, we are in the different suffix
-		}
-		idx1++
-		idx2++
+	if s2.words == nil || s2.Len == uint64(0) { // trying to find common suffix between a string and a void one
+		return s1.subData(0, s1.Len) // we must copy the first array!
 	}
-
-	var (
-		prefixLen       = s2.Len - commonSuffixLen                // length of the different suffix
-		differentPrefix = New(bitarray.NewBitArray(prefixLen), 0) // init a new BitData to keep suffix
-	)
-	for i := uint64(0); i < prefixLen; i++ {
-		if bit, err := s2.GetBit(commonSuffixLen + i); err == nil {
-			differentPrefix.AppendBit(bit)
-		} else {
-			return nil, err
-		}
+	commonSuffixLen, err := s1.CommonSuffixLen(s2) // length of the common suffix
+	if err != nil {
+		return nil, err
 	}
-	return differentPrefix, nil
+	return s2.subData(commonSuffixLen, s2.Len-commonSuffixLen)
 }
 
 // BitToByte returns a byte array in which each byte represents
@@ -232,23 +158,15 @@ func (s1 *BitData) BitToByte() ([]byte, error) {
 		return nil, ErrInvalidString
 	}
 	var (
-		lenInBytes  = s1.Len / 8               // number of characters in the string
-		finalBytes  = make([]byte, lenInBytes) // byte array containing characters of the encoded string
-		currentByte = lenInBytes - 1           // current byte at the iteration; initially the last one
-		currentBit  uint                       // current bit at the iteration; initially 0
+		lenInBytes = s1.Len / 8               // number of characters in the string
+		finalBytes = make([]byte, lenInBytes) // byte array containing characters of the encoded string
 	)
-	for i := uint64(0); i < s1.Len; i++ {
-		bit, err := s1.GetBit(i) // retrieve current bit
-		if err != nil {          // an error occurred
-			return nil, err
-		}
-		if bit { // bit set to 1
-			finalBytes[currentByte] |= 1 << currentBit // let's set the correspective in the byte
-		}
-		if currentBit == 7 { // last bit, let's switch to the other byte
-			currentByte--
-		}
-		currentBit = (currentBit + 1) % 8 // cyclic decrement
+	words, err := s1.GetWords(0, s1.Len)
+	if err != nil {
+		return nil, err
+	}
+	for k := uint64(0); k < lenInBytes; k++ { // the k-th byte of the words is the k-th character from the end
+		finalBytes[lenInBytes-1-k] = byte(words[k/8] >> (8 * (k % 8)))
 	}
 	return finalBytes, nil
 }
@@ -272,13 +190,11 @@ func (s1 *BitData) BitToTrimmedString() (string, error) {
 	if len(bt) == 0 {
 		return "", nil
 	}
-	for bt[0] == byte(0) {
+	for len(bt) > 0 && bt[0] == byte(0) {
 		bt = bt[1:]
 	}
-	r := len(bt) - 1
-	for bt[r] == byte(0) {
-		bt = bt[:r]
-		r = len(bt) - 1
+	for len(bt) > 0 && bt[len(bt)-1] == byte(0) {
+		bt = bt[:len(bt)-1]
 	}
 	if err != nil {
 		return "", err
@@ -302,21 +218,10 @@ func (s1 *BitData) BitToStringOfLengthL(l uint64) (string, error) {
 
 // GetFirstLBits return the first l bits of a BitData
 func (s1 *BitData) GetFirstLBits(l uint64) (*BitData, error) {
-	var (
-		newBd = New(bitarray.NewBitArray(l), l)
-	)
-
-	for i := uint64(0); i < l; i++ {
-		lastBit, err := s1.GetBit(s1.Len - 1 - i)
-		if err != nil {
-			return nil, err
-		}
-		if lastBit {
-			newBd.SetBit(newBd.Len - 1 - i)
-		}
+	if l > s1.Len {
+		return nil, ErrIndexOutOfBound
 	}
-	return newBd, nil
-
+	return s1.subData(s1.Len-l, l) // the first bits are the most significant ones
 }
 
 // GetTotalBitCount , given a slice of string, returns the
@@ -331,6 +236,7 @@ func GetTotalBitCount(strings []string) uint64 {
 }
 
 // Select1 (B,i) with 1 <= i <= n returns the position in B of the i-th occurrence of 1.
+// If the BitData has a rank/select directory (see BuildIndex) the array is not scanned.
 func (s1 *BitData) Select1(i uint64) (uint64, error) {
 	var (
 		onesCount uint64 // number 1s found
@@ -340,45 +246,73 @@ func (s1 *BitData) Select1(i uint64) (uint64, error) {
 	if err := checkIndex(s1, i); err != nil {
 		return uint64(0), err
 	}
+	if s1.HasIndex() {
+		return s1.select1(i)
+	}
 
-	// let's iterate on the array
-	for j := uint64(0); j < s1.Len; j++ {
-		if bit, err := s1.GetBit(j); err == nil {
-			if bit {
-				onesCount++
-			}
-		} else { // error == nil
-			return uint64(0), err
-		}
-		if onesCount == i { // found the i-th occurrence of 1
-			return j, nil
+	// let's iterate on the array, a word at a time
+	for b := uint64(0); b < wordsCount(s1.Len); b++ {
+		word := s1.getWord(b)
+		ones := uint64(bits.OnesCount64(word))
+		if onesCount+ones >= i { // found the word containing the i-th occurrence of 1
+			return b*blockSize + selectInWord(word, i-onesCount), nil
 		}
+		onesCount += ones
 	}
 	return uint64(0), ErrLessThanIOnes
 }
 
 // Rank1 (B,i) returns the number of 1s in the prefix B[1...i] aka B[0...i-1].
+// If the BitData has a rank/select directory (see BuildIndex) the array is not scanned.
 func (s1 *BitData) Rank1(i uint64) (uint64, error) {
+	// assertion check on the index
+	if err := checkIndex(s1, i); err != nil {
+		return uint64(0), err
+	}
+	if s1.HasIndex() {
+		return s1.rank1(i)
+	}
+	return s1.PopCount(0, i) // let's count on the array
+}
+
+// Select0 (B,i) with 1 <= i <= n returns the position in B of the i-th occurrence of 0.
+// If the BitData has a rank/select directory (see BuildIndex) the array is not scanned.
+func (s1 *BitData) Select0(i uint64) (uint64, error) {
 	var (
-		onesCount uint64 // number 1s found
+		zerosCount uint64 // number 0s found
 	)
 
 	// assertion check on the index
 	if err := checkIndex(s1, i); err != nil {
 		return uint64(0), err
 	}
+	if s1.HasIndex() {
+		return s1.select0(i)
+	}
 
-	// let's iterate on the array
-	for j := uint64(0); j < i; j++ {
-		if bit, err := s1.GetBit(j); err == nil {
-			if bit {
-				onesCount++
-			}
-		} else { // error == nil
-			return uint64(0), err
+	// let's iterate on the array, a word at a time
+	for b := uint64(0); b < wordsCount(s1.Len); b++ {
+		var (
+			word  = s1.getWord(b)
+			width = minUint64(blockSize, s1.Len-b*blockSize) // the last word may be shorter
+			zeros = width - uint64(bits.OnesCount64(word))
+		)
+		if zerosCount+zeros >= i { // found the word containing the i-th occurrence of 0
+			return b*blockSize + selectInWord(^word, i-zerosCount), nil
 		}
+		zerosCount += zeros
 	}
-	return onesCount, nil
+	return uint64(0), ErrLessThanIZeros
+}
+
+// Rank0 (B,i) returns the number of 0s in the prefix B[1...i] aka B[0...i-1].
+// If the BitData has a rank/select directory (see BuildIndex) the array is not scanned.
+func (s1 *BitData) Rank0(i uint64) (uint64, error) {
+	ones, err := s1.Rank1(i)
+	if err != nil {
+		return uint64(0), err
+	}
+	return i - ones, nil
 }
 
 func checkIndex(s1 *BitData, i uint64) error {
@@ -411,5 +345,92 @@ func (s1 *BitData) String() string {
 		}
 		i--
 	}
-	return fmt.Sprintf("type: %T, bits:%v, Len:%v, readableBitData:%s", s1, s1.bits, s1.Len, s)
+	return fmt.Sprintf("type: %T, words:%v, Len:%v, readableBitData:%s", s1, s1.words, s1.Len, s)
+}
+
+// GetBits returns the n bits (with n <= 64) in positions [index, index+n) as
+// a word, where the bit in position index is the least significant one.
+func (s1 *BitData) GetBits(index uint64, n uint64) (uint64, error) {
+	if n > blockSize {
+		return uint64(0), ErrInvalidI
+	}
+	if index+n > s1.Len || index+n < index {
+		return uint64(0), ErrIndexOutOfBound
+	}
+	return s1.readBits(index, n), nil
+}
+
+// PopCount returns the number of 1s in positions [from, to), counted a word at a time.
+func (s1 *BitData) PopCount(from uint64, to uint64) (uint64, error) {
+	if from > to || to > s1.Len {
+		return uint64(0), ErrIndexOutOfBound
+	}
+	var count uint64
+	for from < to {
+		width := minUint64(blockSize-from%blockSize, to-from) // up to the end of the word
+		count += uint64(bits.OnesCount64(s1.readBits(from, width)))
+		from += width
+	}
+	return count, nil
+}
+
+// SizeInBytes returns the memory taken by the BitData: the structure itself, the words
+// storing the bits, including the capacity not used yet, and the rank/select directory.
+func (s1 *BitData) SizeInBytes() uint64 {
+	size := uint64(unsafe.Sizeof(*s1)) + 8*uint64(cap(s1.words))
+	if s1.index != nil { // even if it is no longer valid, it is still in memory
+		size += s1.index.sizeInBytes()
+	}
+	return size
+}
+
+// Capacity returns the number of bits the BitData can hold before its storage has to grow.
+func (s1 *BitData) Capacity() uint64 {
+	return blockSize * uint64(len(s1.words))
+}
+
+// Grow makes room in the BitData to append at least n bits, at least doubling its capacity
+// when it is full, so that appending one bit at a time takes amortised constant time.
+// The appends grow the BitData by themselves: Grow only saves the intermediate steps.
+// The bits and the rank/select directory are kept.
+func (s1 *BitData) Grow(n uint64) {
+	capacity := s1.Capacity()
+	if s1.Len+n <= capacity {
+		return
+	}
+	if capacity *= 2; capacity < s1.Len+n {
+		capacity = s1.Len + n
+	}
+	s1.resize(capacity)
+}
+
+// Reserve makes room in the BitData to append at least n bits, without doubling its capacity:
+// it is meant to be called once the number of bits to append is known.
+// The bits and the rank/select directory are kept.
+func (s1 *BitData) Reserve(n uint64) {
+	if s1.Len+n > s1.Capacity() {
+		s1.resize(s1.Len + n)
+	}
+}
+
+// Shrink releases the storage of the BitData that is not used by its Len bits,
+// for example once a structure has been built and no more bits will be appended.
+// The bits and the rank/select directory are kept.
+func (s1 *BitData) Shrink() {
+	if uint64(cap(s1.words)) > wordsCount(s1.Len) {
+		s1.resize(s1.Len)
+	}
+}
+
+// resize moves the bits of the BitData in a storage of the given capacity,
+// that must not be less than Len.
+func (s1 *BitData) resize(capacity uint64) {
+	words := make([]uint64, wordsCount(capacity))
+	copy(words, s1.words)
+	s1.words = words
+}
+
+// wordsCount returns the number of words of 64 bits needed to store n bits.
+func wordsCount(n uint64) uint64 {
+	return (n + blockSize - 1) / blockSize
 }
@@ -14,10 +14,16 @@ var (
 	ErrInvalidString = errors.New("bitdata should be a valid string")
 	// ErrLessThanIOnes is returned by Select1 or Rank1 when you are trying to access to a "1" that does not exist
 	ErrLessThanIOnes = errors.New("there are less than i 1s in the array")
+	// ErrLessThanIZeros is returned by Select0 when you are trying to access to a "0" that does not exist
+	ErrLessThanIZeros = errors.New("there are less than i 0s in the array")
+	// ErrNoOne is returned by NextOne or PrevOne when there is no "1" in the direction of the scan
+	ErrNoOne = errors.New("there are no 1s in the scanned part of the array")
 	// ErrInvalidI is returned when i is greater than the length of the array
 	ErrInvalidI = errors.New("i should not be greater than the length of the array")
 	// ErrZeroI is returned when you are passing a value of i equal to 0
 	ErrZeroI = errors.New("i should be greater than 0")
+	// ErrInvalidFormat is returned by ReadFrom when the data read is not a BitData written by WriteTo
+	ErrInvalidFormat = errors.New("invalid BitData format")
 )
 
 // ErrInvalidPosition is returned when you are trying to access to an invalid position
@@ -0,0 +1,180 @@
+package bitdata
+
+import (
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+const (
+	// blockSize is the number of bits covered by each entry of the blocks directory.
+	blockSize = 64
+	// superBlockSize is the number of bits covered by each entry of the superblocks directory.
+	superBlockSize = 1 << 16
+	// blocksPerSuperBlock is the number of blocks contained in a superblock.
+	blocksPerSuperBlock = superBlockSize / blockSize
+	// selectSampleRate tells every how many 1s (or 0s) we sample the block containing the 1 (or the 0).
+	selectSampleRate = 512
+)
+
+// rankSelectIndex is a succinct directory over a BitData that answers
+// Rank1 and Rank0 in constant time and Select1 and Select0 in (almost) constant time.
+// It is made of three levels:
+//   - superBlocks[k] is the number of 1s before the k-th superblock;
+//   - blocks[j] is the number of 1s before the j-th block, relative to its superblock;
+//   - samples[k] is the block containing the (k*selectSampleRate + 1)-th 1,
+//     and zeroSamples[k] the one containing the (k*selectSampleRate + 1)-th 0.
+//
+// The number of 0s before a block is the number of bits before it minus the number of 1s.
+type rankSelectIndex struct {
+	// Len of the BitData covered by the index.
+	len uint64
+	// Total number of 1s in the BitData covered by the index.
+	ones        uint64
+	superBlocks []uint64
+	blocks      []uint16
+	samples     []uint64
+	zeroSamples []uint64
+}
+
+// BuildIndex builds the rank/select directory on the BitData, so that
+// subsequent calls to Rank1, Rank0, Select1 and Select0 do not need to scan the array.
+// The directory is kept up to date by AppendBit and AppendBits, while it is
+// dropped by SetBit and ClearBit (and ignored if Len is changed by hand):
+// in those cases they fall back to a linear scan until
+// BuildIndex is called again.
+func (s1 *BitData) BuildIndex() error {
+	index := &rankSelectIndex{}
+	for j := uint64(0); j < s1.Len; j++ {
+		bit, err := s1.GetBit(j)
+		if err != nil {
+			return err
+		}
+		index.append(bit)
+	}
+	s1.index = index
+	return nil
+}
+
+// HasIndex returns true if the BitData has a valid rank/select directory.
+func (s1 *BitData) HasIndex() bool {
+	return s1.index != nil && s1.index.len == s1.Len
+}
+
+// append updates the directory after a bit has been appended to the BitData.
+func (index *rankSelectIndex) append(bit bool) {
+	position := index.len
+	if position%superBlockSize == 0 { // we are starting a new superblock
+		index.superBlocks = append(index.superBlocks, index.ones)
+	}
+	if position%blockSize == 0 { // we are starting a new block
+		lastSuperBlock := index.superBlocks[len(index.superBlocks)-1]
+		index.blocks = append(index.blocks, uint16(index.ones-lastSuperBlock))
+	}
+	if bit {
+		if index.ones%selectSampleRate == 0 { // this is the (k*selectSampleRate + 1)-th 1
+			index.samples = append(index.samples, position/blockSize)
+		}
+		index.ones++
+	} else if (position-index.ones)%selectSampleRate == 0 { // this is the (k*selectSampleRate + 1)-th 0
+		index.zeroSamples = append(index.zeroSamples, position/blockSize)
+	}
+	index.len++
+}
+
+// onesBeforeBlock returns the number of 1s before the block b.
+func (index *rankSelectIndex) onesBeforeBlock(b uint64) uint64 {
+	return index.superBlocks[b/blocksPerSuperBlock] + uint64(index.blocks[b])
+}
+
+// zerosBeforeBlock returns the number of 0s before the block b.
+func (index *rankSelectIndex) zerosBeforeBlock(b uint64) uint64 {
+	return b*blockSize - index.onesBeforeBlock(b)
+}
+
+// rank1 returns the number of 1s in the prefix B[0...i-1] using the directory.
+func (s1 *BitData) rank1(i uint64) (uint64, error) {
+	index := s1.index
+	if i == index.len {
+		return index.ones, nil
+	}
+	b := i / blockSize
+	word := s1.getWord(b)
+	mask := uint64(1)<<(i%blockSize) - 1 // only the bits before i in the block
+	return index.onesBeforeBlock(b) + uint64(bits.OnesCount64(word&mask)), nil
+}
+
+// select1 returns the position of the i-th occurrence of 1 using the directory.
+func (s1 *BitData) select1(i uint64) (uint64, error) {
+	index := s1.index
+	if i > index.ones {
+		return uint64(0), ErrLessThanIOnes
+	}
+	var (
+		sample = (i - 1) / selectSampleRate
+		lo     = index.samples[sample]         // block containing the sampled 1 before the i-th one
+		hi     = uint64(len(index.blocks)) - 1 // last block that may contain the i-th one
+	)
+	if sample+1 < uint64(len(index.samples)) {
+		hi = index.samples[sample+1]
+	}
+	// the i-th 1 is in the last block having less than i 1s before it
+	b := lo + uint64(sort.Search(int(hi-lo+1), func(k int) bool {
+		return index.onesBeforeBlock(lo+uint64(k)) >= i
+	})) - 1
+	return b*blockSize + selectInWord(s1.getWord(b), i-index.onesBeforeBlock(b)), nil
+}
+
+// select0 returns the position of the i-th occurrence of 0 using the directory.
+func (s1 *BitData) select0(i uint64) (uint64, error) {
+	index := s1.index
+	if i > index.len-index.ones {
+		return uint64(0), ErrLessThanIZeros
+	}
+	var (
+		sample = (i - 1) / selectSampleRate
+		lo     = index.zeroSamples[sample]     // block containing the sampled 0 before the i-th one
+		hi     = uint64(len(index.blocks)) - 1 // last block that may contain the i-th one
+	)
+	if sample+1 < uint64(len(index.zeroSamples)) {
+		hi = index.zeroSamples[sample+1]
+	}
+	// the i-th 0 is in the last block having less than i 0s before it
+	b := lo + uint64(sort.Search(int(hi-lo+1), func(k int) bool {
+		return index.zerosBeforeBlock(lo+uint64(k)) >= i
+	})) - 1
+	return b*blockSize + selectInWord(^s1.getWord(b), i-index.zerosBeforeBlock(b)), nil
+}
+
+// selectInWord returns the position in word of its k-th 1, that must exist.
+func selectInWord(word uint64, k uint64) uint64 {
+	for ; k > 1; k-- { // drop the 1s preceding the one we are looking for
+		word &= word - 1
+	}
+	return uint64(bits.TrailingZeros64(word))
+}
+
+// getWord returns the b-th block of 64 bits of the BitData, where
+// the bit in position b*64 is the least significant one and the bits after Len are 0s.
+func (s1 *BitData) getWord(b uint64) uint64 {
+	word := s1.words[b]
+	if last := (b + 1) * blockSize; last > s1.Len { // the bits after Len are not part of the BitData
+		word &= uint64(1)<<(s1.Len%blockSize) - 1
+	}
+	return word
+}
+
+// IndexSize returns the size in bits of the memory taken by the rank/select directory,
+// or 0 if the BitData has no valid directory.
+func (s1 *BitData) IndexSize() uint64 {
+	if !s1.HasIndex() {
+		return uint64(0)
+	}
+	return 8 * s1.index.sizeInBytes()
+}
+
+// sizeInBytes returns the memory taken by the directory, including the capacity of its slices.
+func (index *rankSelectIndex) sizeInBytes() uint64 {
+	return uint64(unsafe.Sizeof(*index)) + 8*uint64(cap(index.superBlocks)) + 2*uint64(cap(index.blocks)) +
+		8*uint64(cap(index.samples)) + 8*uint64(cap(index.zeroSamples))
+}
@@ -0,0 +1,59 @@
+package bitdata
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WriteTo writes the BitData to w as its length in bits followed by
+// the bits packed in 64 bits little endian words.
+// It implements the io.WriterTo interface.
+func (s1 *BitData) WriteTo(w io.Writer) (int64, error) {
+	words := make([]uint64, wordsCount(s1.Len))
+	for b := range words {
+		words[b] = s1.getWord(uint64(b))
+	}
+	if err := binary.Write(w, binary.LittleEndian, s1.Len); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(w, binary.LittleEndian, words); err != nil {
+		return 8, err
+	}
+	return int64(8 * (1 + len(words))), nil
+}
+
+// maxReadLen is the greatest length accepted by ReadFrom,
+// so that the words can be indexed on every platform.
+const maxReadLen = uint64(math.MaxInt32) * 64
+
+// readChunkWords is the number of words read at a time by ReadFrom:
+// the memory taken grows with the data actually read, whatever length is read.
+const readChunkWords = uint64(1 << 16)
+
+// ReadFrom replaces the content of the BitData with the one read from r,
+// as written by WriteTo. The rank/select directory is not restored.
+// It returns ErrInvalidFormat if the length read is too large.
+// It implements the io.ReaderFrom interface.
+func (s1 *BitData) ReadFrom(r io.Reader) (int64, error) {
+	var length uint64
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return 0, err
+	}
+	if length > maxReadLen {
+		return 8, ErrInvalidFormat
+	}
+	count := wordsCount(length)
+	words := make([]uint64, 0, minUint64(count, readChunkWords))
+	for uint64(len(words)) < count {
+		chunk := make([]uint64, minUint64(count-uint64(len(words)), readChunkWords))
+		if err := binary.Read(r, binary.LittleEndian, chunk); err != nil {
+			return int64(8 * (1 + len(words))), err
+		}
+		words = append(words, chunk...)
+	}
+	s1.words = words
+	s1.Len = length
+	s1.index = nil
+	return int64(8 * (1 + len(words))), nil
+}
@@ -0,0 +1,200 @@
+package bitdata
+
+import (
+	"math/bits"
+)
+
+// AppendWord appends the n least significant bits of word (with n <= 64) to the BitData,
+// from the least significant one, so that the bit in position i of word is in position
+// Len+i of the BitData. The BitData grows if it is full.
+// It returns ErrInvalidI if n is greater than 64.
+func (s1 *BitData) AppendWord(word uint64, n uint64) error {
+	if n > blockSize {
+		return ErrInvalidI
+	}
+	if n == 0 {
+		return nil
+	}
+	s1.Grow(n)
+	s1.writeBits(s1.Len, word, n)
+	if s1.HasIndex() { // keep the rank/select directory up to date
+		for j := uint64(0); j < n; j++ {
+			s1.index.append(word>>j&1 == 1)
+		}
+	} else {
+		s1.index = nil
+	}
+	s1.Len += n
+	return nil
+}
+
+// GetWords returns the n bits in positions [index, index+n) packed in words of 64 bits,
+// where the bit in position index is the least significant bit of the first word.
+func (s1 *BitData) GetWords(index uint64, n uint64) ([]uint64, error) {
+	if index+n > s1.Len || index+n < index {
+		return nil, ErrIndexOutOfBound
+	}
+	words := make([]uint64, wordsCount(n))
+	for k := range words {
+		offset := uint64(k) * blockSize
+		words[k] = s1.readBits(index+offset, minUint64(blockSize, n-offset))
+	}
+	return words, nil
+}
+
+// CommonPrefixLen returns the length of the longest common prefix of s1 and s2, where
+// the prefix of a BitData is made of its most significant bits: since the first character
+// of a string is stored in the most significant bits, it is the prefix of the string.
+// The BitData are compared a word at a time.
+func (s1 *BitData) CommonPrefixLen(s2 *BitData) (uint64, error) {
+	var (
+		n      = minUint64(s1.Len, s2.Len)
+		common uint64
+	)
+	for common < n {
+		width := minUint64(blockSize, n-common)
+		w1 := s1.readBits(s1.Len-common-width, width)
+		w2 := s2.readBits(s2.Len-common-width, width)
+		if diff := w1 ^ w2; diff != 0 { // the equal bits are the leading ones of the word
+			return common + uint64(bits.LeadingZeros64(diff<<(blockSize-width))), nil
+		}
+		common += width
+	}
+	return common, nil
+}
+
+// CommonSuffixLen returns the length of the longest common suffix of s1 and s2, where
+// the suffix of a BitData is made of its least significant bits.
+// The BitData are compared a word at a time.
+func (s1 *BitData) CommonSuffixLen(s2 *BitData) (uint64, error) {
+	var (
+		n      = minUint64(s1.Len, s2.Len)
+		common uint64
+	)
+	for common < n {
+		width := minUint64(blockSize, n-common)
+		w1 := s1.readBits(common, width)
+		w2 := s2.readBits(common, width)
+		if diff := w1 ^ w2; diff != 0 { // the equal bits are the trailing ones of the word
+			return common + uint64(bits.TrailingZeros64(diff)), nil
+		}
+		common += width
+	}
+	return common, nil
+}
+
+// NextOne returns the position of the first 1 in positions [i, Len), or ErrNoOne
+// if they are all 0s. Whole words of 0s are skipped at once.
+func (s1 *BitData) NextOne(i uint64) (uint64, error) {
+	if i >= s1.Len {
+		return uint64(0), ErrIndexOutOfBound
+	}
+	var (
+		b    = i / blockSize
+		last = wordsCount(s1.Len) - 1
+		word = s1.getWord(b) &^ (uint64(1)<<(i%blockSize) - 1) // drop the bits before i
+	)
+	for word == 0 {
+		if b == last {
+			return uint64(0), ErrNoOne
+		}
+		b++
+		word = s1.getWord(b)
+	}
+	return b*blockSize + uint64(bits.TrailingZeros64(word)), nil
+}
+
+// PrevOne returns the position of the last 1 in positions [0, i], or ErrNoOne
+// if they are all 0s. Whole words of 0s are skipped at once.
+func (s1 *BitData) PrevOne(i uint64) (uint64, error) {
+	if i >= s1.Len {
+		return uint64(0), ErrIndexOutOfBound
+	}
+	var (
+		b    = i / blockSize
+		word = s1.getWord(b)
+	)
+	if shift := i % blockSize; shift < blockSize-1 { // drop the bits after i
+		word &= uint64(1)<<(shift+1) - 1
+	}
+	for word == 0 {
+		if b == 0 {
+			return uint64(0), ErrNoOne
+		}
+		b--
+		word = s1.getWord(b)
+	}
+	return b*blockSize + blockSize - 1 - uint64(bits.LeadingZeros64(word)), nil
+}
+
+// AppendRange appends to s1 the n bits of s2 in positions [index, index+n), a word at a time.
+// The BitData grows if it is full.
+func (s1 *BitData) AppendRange(s2 *BitData, index uint64, n uint64) error {
+	if index+n > s2.Len || index+n < index {
+		return ErrIndexOutOfBound
+	}
+	for n > 0 {
+		width := minUint64(blockSize, n)
+		if err := s1.AppendWord(s2.readBits(index, width), width); err != nil {
+			return err
+		}
+		index += width
+		n -= width
+	}
+	return nil
+}
+
+// subData returns a new BitData containing the n bits of s1 in positions [index, index+n).
+func (s1 *BitData) subData(index uint64, n uint64) (*BitData, error) {
+	sub := New(n, 0)
+	if err := sub.AppendRange(s1, index, n); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// readBits returns the n bits (with n <= 64) in positions [index, index+n) as a word,
+// reading at most the two words of the storage containing them.
+// The positions must be in the storage, but they may be after Len.
+func (s1 *BitData) readBits(index uint64, n uint64) uint64 {
+	if n == 0 {
+		return uint64(0)
+	}
+	var (
+		w     = index / blockSize
+		shift = index % blockSize
+		word  = s1.words[w] >> shift
+	)
+	if shift+n > blockSize { // the bits continue in the next word
+		word |= s1.words[w+1] << (blockSize - shift)
+	}
+	if n < blockSize {
+		word &= uint64(1)<<n - 1
+	}
+	return word
+}
+
+// writeBits overwrites the bits in positions [index, index+n) (with 0 < n <= 64) with
+// the n least significant bits of word. The positions must be in the storage.
+func (s1 *BitData) writeBits(index uint64, word uint64, n uint64) {
+	var (
+		w     = index / blockSize
+		shift = index % blockSize
+		mask  = ^uint64(0)
+	)
+	if n < blockSize {
+		mask = uint64(1)<<n - 1
+	}
+	word &= mask
+	s1.words[w] = s1.words[w]&^(mask<<shift) | word<<shift
+	if shift+n > blockSize { // the bits continue in the next word
+		s1.words[w+1] = s1.words[w+1]&^(mask>>(blockSize-shift)) | word>>(blockSize-shift)
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -2,9 +2,11 @@ package stringcoding
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/dariodip/prefix-search/prefix-search/bitdata"
-	"github.com/golang-collections/go-datastructures/bitarray"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"math/rand"
 	"testing"
 )
 
@@ -13,7 +15,7 @@ func TestBitData_GetBit(t *testing.T) {
 		a              = assert.New(t)
 		checkBit       = []bool{false, true, false}
 		expectedLength uint64
-		bd             = bitdata.New(bitarray.NewBitArray(8), 0)
+		bd             = bitdata.New(8, 0)
 	)
 	a.Equal(expectedLength, bd.Len, "Initially the BitData is empty")
 	for _, bit := range checkBit {
@@ -35,7 +37,7 @@ func TestBitData_GetBit(t *testing.T) {
 func TestBitData_SetBit(t *testing.T) {
 	var (
 		a        = assert.New(t)
-		bd       = bitdata.New(bitarray.NewBitArray(8), 0)
+		bd       = bitdata.New(8, 0)
 		checkBit = []bool{false, true, true}
 	)
 
@@ -61,8 +63,8 @@ func TestBitData_AppendBits(t *testing.T) {
 		a              = assert.New(t)
 		checkBit       = []bool{false, true, false}
 		expectedLength uint64
-		bd             = bitdata.New(bitarray.NewBitArray(8), 0)
-		bd2            = bitdata.New(bitarray.NewBitArray(8), 0)
+		bd             = bitdata.New(8, 0)
+		bd2            = bitdata.New(8, 0)
 	)
 	a.Equal(expectedLength, bd.Len, "Initially the BitData is empty")
 	for _, bit := range checkBit {
@@ -315,3 +317,423 @@ func TestRank1(t *testing.T) {
 	a.NotNil(errRank133, "error message should not be nil")
 
 }
+
+func TestRankSelectIndex(t *testing.T) {
+	const n = uint64(3*(1<<16) + 123) // more than one superblock
+	var (
+		a          = assert.New(t)
+		plain, _   = randomBitData(rand.New(rand.NewSource(42)), n)
+		indexed, _ = randomBitData(rand.New(rand.NewSource(42)), n)
+	)
+	a.NoError(indexed.BuildIndex())
+	a.True(indexed.HasIndex(), "the directory should be built")
+	a.False(plain.HasIndex(), "the directory should not be built")
+
+	for i := uint64(1); i <= n; i += 97 {
+		expected, err := plain.Rank1(i)
+		a.Nil(err)
+		got, err := indexed.Rank1(i)
+		a.Nil(err)
+		a.Equal(expected, got, "rank1(%d) mismatch", i)
+	}
+	ones, err := plain.Rank1(n)
+	a.Nil(err)
+	for i := uint64(1); i <= ones; i += 89 {
+		expected, err := plain.Select1(i)
+		a.Nil(err)
+		got, err := indexed.Select1(i)
+		a.Nil(err)
+		a.Equal(expected, got, "select1(%d) mismatch", i)
+	}
+	lastOne, err := plain.Select1(ones)
+	a.Nil(err)
+	got, err := indexed.Select1(ones)
+	a.Nil(err)
+	a.Equal(lastOne, got, "select1 on the last 1 mismatch")
+
+	_, err = indexed.Select1(ones + 1)
+	a.Equal(bitdata.ErrLessThanIOnes, err, "there are no more 1s")
+	_, err = indexed.Rank1(0)
+	a.Equal(bitdata.ErrZeroI, err, "i should be greater than 0")
+	_, err = indexed.Rank1(n + 1)
+	a.Equal(bitdata.ErrInvalidI, err, "i should not be greater than the length")
+}
+
+func TestRankSelectIndexUpdate(t *testing.T) {
+	var (
+		a          = assert.New(t)
+		plain, _   = randomBitData(rand.New(rand.NewSource(7)), 1000)
+		indexed, _ = randomBitData(rand.New(rand.NewSource(7)), 1000)
+	)
+	a.NoError(indexed.BuildIndex())
+
+	// appending bits keeps the directory up to date
+	for i := 0; i < 200; i++ {
+		plain.AppendBit(i%5 == 0)
+		indexed.AppendBit(i%5 == 0)
+	}
+	a.True(indexed.HasIndex(), "AppendBit should update the directory")
+	expected, _ := plain.Rank1(plain.Len)
+	got, _ := indexed.Rank1(indexed.Len)
+	a.Equal(expected, got, "rank1 after append mismatch")
+	expected, _ = plain.Select1(expected)
+	got, _ = indexed.Select1(got)
+	a.Equal(expected, got, "select1 after append mismatch")
+
+	// setting a bit drops the directory
+	for i := uint64(0); i < indexed.Len; i++ {
+		if bit, _ := indexed.GetBit(i); !bit {
+			plain.SetBit(i)
+			indexed.SetBit(i)
+			break
+		}
+	}
+	a.False(indexed.HasIndex(), "SetBit should drop the directory")
+	expected, _ = plain.Rank1(plain.Len)
+	got, _ = indexed.Rank1(indexed.Len)
+	a.Equal(expected, got, "rank1 after set mismatch")
+
+	// changing Len by hand makes the directory stale
+	indexed.BuildIndex()
+	indexed.Len--
+	a.False(indexed.HasIndex(), "a directory on a different Len is not valid")
+}
+
+func TestBitData_Grow(t *testing.T) {
+	var (
+		a  = assert.New(t)
+		bd = bitdata.New(0, 0)
+	)
+	for i := 0; i < 1000; i++ {
+		bd.Grow(1)
+		a.Nil(bd.AppendBit(i%3 == 0), "AppendBit should not fail after Grow")
+		if i == 500 {
+			a.Nil(bd.BuildIndex(), "BuildIndex should not fail")
+		}
+	}
+	a.True(bd.HasIndex(), "Grow should keep the directory")
+	for i := uint64(0); i < bd.Len; i++ {
+		bit, err := bd.GetBit(i)
+		a.Nil(err)
+		a.Equal(i%3 == 0, bit, "wrong bit at position %d after Grow", i)
+	}
+	rank, _ := bd.Rank1(bd.Len)
+	a.Equal(uint64(334), rank, "rank1 after Grow mismatch")
+}
+
+func TestBitData_AppendPastCapacity(t *testing.T) {
+	var (
+		a     = assert.New(t)
+		bd    = bitdata.New(64, 64) // the bit array is full
+		empty = &bitdata.BitData{}
+	)
+	a.NoError(bd.AppendBit(true), "AppendBit should grow a full BitData")
+	a.NoError(bd.AppendWord(0x5, 3), "AppendWord should grow a full BitData")
+	a.NoError(empty.AppendWord(0x5, 3), "AppendWord should grow an empty BitData")
+	a.Equal(uint64(68), bd.Len)
+	word, err := bd.GetBits(64, 4)
+	a.NoError(err)
+	a.Equal(uint64(0xB), word, "the bits appended past the capacity should be kept")
+	word, err = empty.GetBits(0, 3)
+	a.NoError(err)
+	a.Equal(uint64(0x5), word, "the bits appended to an empty BitData should be kept")
+}
+
+func TestBitData_ReserveAndShrink(t *testing.T) {
+	var (
+		a  = assert.New(t)
+		bd = bitdata.New(0, 0)
+	)
+	bd.Reserve(1000)
+	a.True(bd.Capacity() >= 1000, "Reserve should make room for the bits")
+	for i := 0; i < 100; i++ {
+		a.NoError(bd.AppendBit(i%7 == 0))
+	}
+	a.NoError(bd.BuildIndex())
+	bd.Shrink()
+	a.True(bd.Capacity() < bd.Len+64, "Shrink should release the storage after Len, capacity is %d", bd.Capacity())
+	a.True(bd.HasIndex(), "Shrink should keep the directory")
+	for i := uint64(0); i < bd.Len; i++ {
+		bit, err := bd.GetBit(i)
+		a.NoError(err)
+		a.Equal(i%7 == 0, bit, "wrong bit at position %d after Shrink", i)
+	}
+	a.NoError(bd.AppendBit(true), "AppendBit should grow a shrunk BitData")
+	rank, _ := bd.Rank1(bd.Len)
+	a.Equal(uint64(16), rank, "rank1 after Shrink mismatch")
+}
+
+func TestBitData_AppendWord(t *testing.T) {
+	var (
+		a  = assert.New(t)
+		bd = bitdata.New(200, 0)
+	)
+	a.NoError(bd.AppendWord(0xFF05, 12), "the bits over n should be ignored")
+	a.NoError(bd.AppendWord(^uint64(0), 64))
+	a.NoError(bd.AppendWord(0, 0))
+	a.Equal(uint64(76), bd.Len)
+	for i, want := range []bool{true, false, true, false, false, false, false, false, true, true, true, true} {
+		bit, _ := bd.GetBit(uint64(i))
+		a.Equal(want, bit, "bit %d", i)
+	}
+	ones, _ := bd.Rank1(bd.Len)
+	a.Equal(uint64(6+64), ones)
+	a.Equal(bitdata.ErrInvalidI, bd.AppendWord(0, 65))
+}
+
+func TestBitData_GetWords(t *testing.T) {
+	var (
+		a        = assert.New(t)
+		rnd      = rand.New(rand.NewSource(61))
+		bd, bits = randomBitData(rnd, 300)
+	)
+	for _, r := range [][2]uint64{{0, 0}, {0, 300}, {5, 64}, {63, 130}, {299, 1}} {
+		words, err := bd.GetWords(r[0], r[1])
+		a.NoError(err)
+		a.Len(words, int((r[1]+63)/64))
+		for j := uint64(0); j < r[1]; j++ {
+			a.Equal(bits[r[0]+j], words[j/64]>>(j%64)&1 == 1, "bit %d of [%d, %d)", j, r[0], r[0]+r[1])
+		}
+	}
+	_, err := bd.GetWords(250, 51)
+	a.Equal(bitdata.ErrIndexOutOfBound, err)
+}
+
+func TestBitData_CommonPrefixAndSuffixLen(t *testing.T) {
+	var (
+		a   = assert.New(t)
+		rnd = rand.New(rand.NewSource(62))
+	)
+	for k := 0; k < 200; k++ {
+		bd1, bits1 := randomBitData(rnd, uint64(rnd.Intn(200)))
+		bd2, bits2 := randomBitData(rnd, uint64(rnd.Intn(200)))
+		if k%2 == 0 { // let them share some bits at both the ends
+			shared, sharedBits := randomBitData(rnd, uint64(rnd.Intn(150)))
+			bd1, bd2 = concatBitData(shared, bd1, shared), concatBitData(shared, bd2, shared)
+			bits1 = append(append(append([]bool{}, sharedBits...), bits1...), sharedBits...)
+			bits2 = append(append(append([]bool{}, sharedBits...), bits2...), sharedBits...)
+		}
+		var wantPrefix, wantSuffix uint64
+		for wantPrefix < uint64(len(bits1)) && wantPrefix < uint64(len(bits2)) &&
+			bits1[len(bits1)-1-int(wantPrefix)] == bits2[len(bits2)-1-int(wantPrefix)] {
+			wantPrefix++
+		}
+		for wantSuffix < uint64(len(bits1)) && wantSuffix < uint64(len(bits2)) &&
+			bits1[wantSuffix] == bits2[wantSuffix] {
+			wantSuffix++
+		}
+		prefix, err := bd1.CommonPrefixLen(bd2)
+		a.NoError(err)
+		a.Equal(wantPrefix, prefix, "common prefix of %v and %v", bits1, bits2)
+		suffix, err := bd1.CommonSuffixLen(bd2)
+		a.NoError(err)
+		a.Equal(wantSuffix, suffix, "common suffix of %v and %v", bits1, bits2)
+	}
+}
+
+// concatBitData returns a new BitData with the bits of the given ones, one after the other.
+func concatBitData(bds ...*bitdata.BitData) *bitdata.BitData {
+	var n uint64
+	for _, bd := range bds {
+		n += bd.Len
+	}
+	concat := bitdata.New(n, 0)
+	for _, bd := range bds {
+		concat.AppendBits(bd)
+	}
+	return concat
+}
+
+func TestBitData_PopCount(t *testing.T) {
+	var (
+		a        = assert.New(t)
+		rnd      = rand.New(rand.NewSource(61))
+		bd, bits = randomBitData(rnd, 300)
+	)
+	for _, r := range [][2]uint64{{0, 0}, {0, 300}, {3, 64}, {63, 65}, {64, 128}, {10, 290}, {299, 300}} {
+		var want uint64
+		for _, bit := range bits[r[0]:r[1]] {
+			if bit {
+				want++
+			}
+		}
+		got, err := bd.PopCount(r[0], r[1])
+		a.NoError(err)
+		a.Equal(want, got, "PopCount(%d, %d) mismatch", r[0], r[1])
+	}
+	_, err := bd.PopCount(0, 301)
+	a.Equal(bitdata.ErrIndexOutOfBound, err, "PopCount after Len should fail")
+	_, err = bd.PopCount(5, 4)
+	a.Equal(bitdata.ErrIndexOutOfBound, err, "PopCount on a reversed range should fail")
+}
+
+func TestBitData_SizeInBytes(t *testing.T) {
+	var (
+		a     = assert.New(t)
+		bd    = bitdata.New(1000, 0)
+		empty = bitdata.New(0, 0).SizeInBytes()
+	)
+	a.Equal(empty+16*8, bd.SizeInBytes(), "the storage should take 16 words")
+	for i := 0; i < 100; i++ {
+		a.NoError(bd.AppendBit(i%2 == 0))
+	}
+	bd.Shrink()
+	a.Equal(empty+2*8, bd.SizeInBytes(), "the storage should take 2 words after Shrink")
+	a.NoError(bd.BuildIndex())
+	a.Equal(empty+2*8+bd.IndexSize()/8, bd.SizeInBytes(), "the size should include the directory")
+}
+
+func TestBitData_AppendAfterLenDecrease(t *testing.T) {
+	var (
+		a  = assert.New(t)
+		bd = bitdata.New(128, 0)
+	)
+	a.NoError(bd.AppendWord(^uint64(0), 64))
+	a.NoError(bd.AppendWord(^uint64(0), 64))
+	bd.Len = 60 // the bits after Len are still in the storage
+	a.NoError(bd.AppendWord(0, 10))
+	a.NoError(bd.AppendBit(false))
+	ones, err := bd.PopCount(0, bd.Len)
+	a.NoError(err)
+	a.Equal(uint64(60), ones, "the appended bits should overwrite the old ones")
+	ones, err = bd.Rank1(bd.Len)
+	a.NoError(err)
+	a.Equal(uint64(60), ones, "rank1 should not count the bits after Len")
+}
+
+func TestRankSelect0(t *testing.T) {
+	const n = uint64(2*(1<<16) + 77) // more than one superblock
+	var (
+		a          = assert.New(t)
+		plain, _   = randomBitData(rand.New(rand.NewSource(43)), n)
+		indexed, _ = randomBitData(rand.New(rand.NewSource(43)), n)
+		zeros      uint64
+	)
+	a.NoError(indexed.BuildIndex())
+	for i := uint64(0); i < n; i++ {
+		bit, err := plain.GetBit(i)
+		a.Nil(err)
+		if bit {
+			continue
+		}
+		zeros++
+		if zeros%37 != 1 && i != n-1 { // check a sample of the 0s, and the last bit
+			continue
+		}
+		for _, bd := range []*bitdata.BitData{plain, indexed} {
+			got, err := bd.Select0(zeros)
+			a.Nil(err)
+			a.Equal(i, got, "select0(%d) mismatch, directory: %v", zeros, bd.HasIndex())
+			rank, err := bd.Rank0(i + 1)
+			a.Nil(err)
+			a.Equal(zeros, rank, "rank0(%d) mismatch, directory: %v", i+1, bd.HasIndex())
+		}
+	}
+	for _, bd := range []*bitdata.BitData{plain, indexed} {
+		_, err := bd.Select0(zeros + 1)
+		a.Equal(bitdata.ErrLessThanIZeros, err, "there are no more 0s, directory: %v", bd.HasIndex())
+		_, err = bd.Rank0(0)
+		a.Equal(bitdata.ErrZeroI, err, "i should be greater than 0")
+	}
+
+	ones := bitdata.New(100, 0)
+	for i := 0; i < 100; i++ {
+		ones.AppendBit(true)
+	}
+	a.Nil(ones.BuildIndex())
+	_, err := ones.Select0(1)
+	a.Equal(bitdata.ErrLessThanIZeros, err, "there are no 0s")
+}
+
+func TestBitData_NextOnePrevOne(t *testing.T) {
+	var (
+		a        = assert.New(t)
+		bd       = bitdata.New(1000, 0)
+		onesAt   = map[uint64]bool{3: true, 64: true, 65: true, 400: true, 927: true}
+		nextOnes = make([]int64, 1000)
+	)
+	for i := uint64(0); i < 1000; i++ {
+		bd.AppendBit(onesAt[i])
+	}
+	next := int64(-1)
+	for i := 999; i >= 0; i-- {
+		if onesAt[uint64(i)] {
+			next = int64(i)
+		}
+		nextOnes[i] = next
+	}
+	prev := int64(-1)
+	for i := uint64(0); i < 1000; i++ {
+		if onesAt[i] {
+			prev = int64(i)
+		}
+		got, err := bd.NextOne(i)
+		if nextOnes[i] < 0 {
+			a.Equal(bitdata.ErrNoOne, err, "no 1 after %d", i)
+		} else {
+			a.Nil(err)
+			a.Equal(uint64(nextOnes[i]), got, "NextOne(%d) mismatch", i)
+		}
+		got, err = bd.PrevOne(i)
+		if prev < 0 {
+			a.Equal(bitdata.ErrNoOne, err, "no 1 before %d", i)
+		} else {
+			a.Nil(err)
+			a.Equal(uint64(prev), got, "PrevOne(%d) mismatch", i)
+		}
+	}
+	_, err := bd.NextOne(1000)
+	a.Equal(bitdata.ErrIndexOutOfBound, err)
+	_, err = bd.PrevOne(1000)
+	a.Equal(bitdata.ErrIndexOutOfBound, err)
+}
+
+func TestBitData_WriteToReadFrom(t *testing.T) {
+	var (
+		a      = assert.New(t)
+		bd, _  = randomBitData(rand.New(rand.NewSource(77)), 1000)
+		loaded bitdata.BitData
+		buffer bytes.Buffer
+	)
+	written, err := bd.WriteTo(&buffer)
+	a.Nil(err)
+	read, err := loaded.ReadFrom(&buffer)
+	a.Nil(err)
+	a.Equal(written, read)
+	a.Equal(bd.Len, loaded.Len)
+	for i := uint64(0); i < bd.Len; i++ {
+		want, _ := bd.GetBit(i)
+		got, err := loaded.GetBit(i)
+		a.Nil(err)
+		a.Equal(want, got, "bit %d mismatch", i)
+	}
+
+	header := make([]byte, 8) // a corrupted length, followed by a few words
+	binary.LittleEndian.PutUint64(header, ^uint64(0))
+	_, err = loaded.ReadFrom(bytes.NewReader(append(header, make([]byte, 16)...)))
+	a.Equal(bitdata.ErrInvalidFormat, err, "a length too large should be rejected")
+	binary.LittleEndian.PutUint64(header, uint64(1)<<36)
+	_, err = loaded.ReadFrom(bytes.NewReader(append(header, make([]byte, 16)...)))
+	a.Equal(io.ErrUnexpectedEOF, err, "the words missing after the length should be detected")
+	a.Equal(bd.Len, loaded.Len, "a failed ReadFrom should not change the BitData")
+}
+
+func TestBitData_AppendRange(t *testing.T) {
+	var (
+		a          = assert.New(t)
+		rnd        = rand.New(rand.NewSource(80))
+		src, bits  = randomBitData(rnd, 500)
+		dst, dBits = randomBitData(rnd, 37) // not aligned to a word
+	)
+	for _, r := range [][2]uint64{{0, 0}, {3, 61}, {64, 64}, {100, 300}, {499, 1}} {
+		a.NoError(dst.AppendRange(src, r[0], r[1]))
+		dBits = append(dBits, bits[r[0]:r[0]+r[1]]...)
+	}
+	a.Equal(uint64(len(dBits)), dst.Len)
+	for i, want := range dBits {
+		got, err := dst.GetBit(uint64(i))
+		a.Nil(err)
+		a.Equal(want, got, "bit %d mismatch", i)
+	}
+	a.Equal(bitdata.ErrIndexOutOfBound, dst.AppendRange(src, 400, 101), "the range should be in src")
+}
@@ -9,7 +9,6 @@ package stringcoding
 import (
 	"fmt"
 	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
-	"github.com/golang-collections/go-datastructures/bitarray"
 )
 
 // Coding is a type that contains some of the data structures to run LPRC and PSRC
@@ -20,7 +19,11 @@ type Coding struct {
 	// Starts consists of a sequence of bits in which each bit
 	// set to 1 marks the first bit of each of those suffixes
 	// in the aforementioned array (Strings).
+	// It is nil once it has been replaced by startsEF.
 	Starts *bd.BitData
+	// startsEF, if not nil, contains the positions of the 1s of Starts
+	// with Elias-Fano, see WithEliasFanoStarts.
+	startsEF *eliasFano
 	// Lengths encodes a value associated to each string in strings.
 	// The value depends from the particular compression scheme used
 	Lengths *bd.BitData
@@ -32,38 +35,165 @@ type Coding struct {
 	NextIndex uint64
 	// NextLengthsIndex marks the last index in the Lengths array.
 	NextLengthsIndex uint64
+	// lengthsSamples contains the position in Lengths of a code every
+	// lengthsSampleRate, so that a code can be decoded without
+	// scanning Lengths from the beginning.
+	lengthsSamples []uint64
+	// lengthsCount is the number of codes in Lengths.
+	lengthsCount uint64
+	// coder is the IntCoder used to write the codes in Lengths.
+	coder IntCoder
+	// fitter, if not nil, is the IntCoder that has chosen coder from the codes
+	// in Lengths: it chooses it again when a code cannot be written by coder.
+	fitter fitter
 }
 
 // New creates and returns a new Coding structure inserting the strings
 // that are in the array of strings.
+// The values in Lengths are written with Elias gamma.
 func New(strings []string) *Coding {
+	return NewWithCoder(strings, EliasGammaCoder{})
+}
+
+// NewWithCoder creates and returns a new Coding structure inserting the strings
+// that are in the array of strings, whose values in Lengths are written with coder.
+// The BitData are created with room for the strings, but they grow if more bits are appended.
+func NewWithCoder(strings []string, coder IntCoder) *Coding {
 	maxCapacity := bd.GetTotalBitCount(strings)
 	maxCapacity += uint64(len(strings) * 16)
-	maxLengthCapacity, err := getEliasGammaLength(strings)
-	if err != nil {
-		panic("Cannot find the required capacity")
-	}
+	maxLengthCapacity := getLengthsCapacity(strings, coder)
 	fc := Coding{
-		Strings:          bd.New(bitarray.NewBitArray(maxCapacity), 0),
-		Starts:           bd.New(bitarray.NewBitArray(maxCapacity), 0),
-		Lengths:          bd.New(bitarray.NewBitArray(maxLengthCapacity), 0),
+		Strings:          bd.New(maxCapacity, 0),
+		Starts:           bd.New(maxCapacity, 0),
+		Lengths:          bd.New(maxLengthCapacity, 0),
 		NextLengthsIndex: uint64(0),
+		coder:            coder,
 	}
 	return &fc
 }
 
+// shrink releases the storage reserved for the BitData and not used,
+// once all the strings have been coded.
+func (c *Coding) shrink() {
+	for _, bitData := range c.bitDatas() {
+		bitData.Shrink()
+	}
+}
+
+// bitDatas returns the BitData of the Coding: Strings, Starts, or the low and high bits
+// of startsEF, and Lengths.
+func (c *Coding) bitDatas() []*bd.BitData {
+	if c.startsEF != nil {
+		return []*bd.BitData{c.Strings, c.startsEF.low, c.startsEF.high, c.Lengths}
+	}
+	return []*bd.BitData{c.Strings, c.Starts, c.Lengths}
+}
+
+// encodeStartsWithEliasFano replaces Starts with startsEF, once all the strings have been coded.
+// Nothing is done if it has already been replaced, e.g. when the structure is populated again.
+func (c *Coding) encodeStartsWithEliasFano() error {
+	if c.startsEF != nil {
+		return nil
+	}
+	count, err := c.Starts.PopCount(0, c.Starts.Len)
+	if err != nil {
+		return err
+	}
+	var (
+		ef       = newEliasFano(count, c.Strings.Len)
+		position uint64
+	)
+	for k := uint64(0); k < count; k, position = k+1, position+1 {
+		if position, err = c.Starts.NextOne(position); err != nil {
+			return err
+		}
+		if err := ef.append(position); err != nil {
+			return err
+		}
+	}
+	c.Starts = nil
+	c.startsEF = ef
+	return nil
+}
+
+// start returns the position in Strings of the first bit stored for the i-th string.
+func (c *Coding) start(i uint64) (uint64, error) {
+	if c.startsEF != nil {
+		return c.startsEF.get(i)
+	}
+	return c.Starts.Select1(i + 1)
+}
+
+// buildStartsIndex builds the rank/select directory used by start.
+func (c *Coding) buildStartsIndex() error {
+	if c.startsEF != nil {
+		return c.startsEF.buildIndex()
+	}
+	return c.Starts.BuildIndex()
+}
+
+// hasStartsIndex returns true if the rank/select directory used by start is valid.
+func (c *Coding) hasStartsIndex() bool {
+	if c.startsEF != nil {
+		return c.startsEF.high.HasIndex()
+	}
+	return c.Starts.HasIndex()
+}
+
+// startsSize returns the size in bits of the memory taken by Starts, or by startsEF,
+// and by its rank/select directory.
+func (c *Coding) startsSize() (uint64, uint64) {
+	if c.startsEF != nil {
+		return dataSize(c.startsEF.low) + dataSize(c.startsEF.high), c.startsEF.high.IndexSize()
+	}
+	return dataSize(c.Starts), c.Starts.IndexSize()
+}
+
+// startsEncoding returns the name of the representation of the starts of the strings.
+func (c *Coding) startsEncoding() string {
+	if c.startsEF != nil {
+		return "EliasFano"
+	}
+	return "Bitvector"
+}
+
+// dataSize returns the size in bits of the memory taken by bitData, apart from
+// its rank/select directory, whose size is reported on its own.
+func dataSize(bitData *bd.BitData) uint64 {
+	return 8*bitData.SizeInBytes() - bitData.IndexSize()
+}
+
 // setStartsWithOffset sets the bit in the Starts bitdata in order
 // to state where the suffix in Strings starts.
 func (c *Coding) setStartsWithOffset(differentSuffix *bd.BitData) error {
 	if differentSuffix.Len == 0 {
 		return nil // nothing to do here
 	}
+	if c.startsEF != nil { // the suffix has just been appended to Strings
+		return c.startsEF.append(c.Strings.Len - differentSuffix.Len)
+	}
 
-	c.Starts.AppendBit(true)
-
-	c.Starts.Len += differentSuffix.Len - 1
+	if err := c.Starts.AppendWord(1, 1); err != nil {
+		return err
+	}
+	return appendZeros(c.Starts, differentSuffix.Len-1) // the other bits of the suffix are 0
+}
 
-	return nil
+// getChunk returns the position in Strings of the first bit stored for the
+// i-th string and the number of bits stored for it, given the number of
+// strings in the structure.
+func (c *Coding) getChunk(i uint64, stringsCount uint64) (uint64, uint64, error) {
+	start, err := c.start(i)
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	end := c.Strings.Len // the last string ends with Strings
+	if i+1 < stringsCount {
+		if end, err = c.start(i + 1); err != nil {
+			return uint64(0), uint64(0), err
+		}
+	}
+	return start, end - start, nil
 }
 
 func (c *Coding) String() string {
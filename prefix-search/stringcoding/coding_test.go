@@ -2,7 +2,6 @@ package stringcoding
 
 import (
 	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
-	"github.com/golang-collections/go-datastructures/bitarray"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -73,7 +72,7 @@ func TestCoding_Add(t *testing.T) {
 
 	var (
 		a           = assert.New(t)
-		lprc        = NewLPRC([]string{s1, s2, s3}, epsilon)
+		lprc        = newLPRC(t, []string{s1, s2, s3}, epsilon)
 		stringsBits = []bool{ // expected Strings final state
 			false, false, false, false, false, false, false, false, // null char
 			true, false, false, false, false, true, true, false, // a
@@ -143,7 +142,7 @@ func TestCoding_Add(t *testing.T) {
 	}
 
 	// Lengths
-	s1val, err := lprc.coding.decodeIthEliasGamma(0)
+	s1val, err := lprc.coding.decodeIthLength(0)
 	a.Nil(err, "Something goes wrong: %s", err)
 	a.Equal(s1val, uint64(0), "Some bit are missing in Lengths. Found %d, expected %d", s1val, uint64(0))
 
@@ -167,7 +166,7 @@ func TestCoding_Add(t *testing.T) {
 		"wrong latest compressed bit written. Found %d, expected %d",
 		compressedS2.Len, lprc.latestCompressedBitWritten)
 
-	s2val, err := lprc.coding.decodeIthEliasGamma(1)
+	s2val, err := lprc.coding.decodeIthLength(1)
 	a.Nil(err, "Something goes wrong: %s", err)
 	a.Equal(s2val, uint64(8+2), "Some bit are missing in Lengths. Found %d, expected %d",
 		s2val, uint64(8+2))
@@ -182,7 +181,7 @@ func TestCoding_Add(t *testing.T) {
 	a.Equal(s3bits.Len, lprc.coding.LastString.Len, "Wrong len on LastString, should be %d", s3bits.Len)
 	a.Equal(lprc.latestCompressedBitWritten, uint64(0), "String %s should be uncompressed", s3)
 
-	s3val, err := lprc.coding.decodeIthEliasGamma(2)
+	s3val, err := lprc.coding.decodeIthLength(2)
 	a.Equal(s3val, s2bits.Len, "Wrong bit len for %s. Found %d, expected %d", s3, s3val, s2bits.Len)
 
 	// Check the structure final state
@@ -215,7 +214,7 @@ func TestSetStartsWithOffset(t *testing.T) {
 	var (
 		a            = assert.New(t)
 		c            = New([]string{"ciao"})
-		bitD         = bd.New(bitarray.NewBitArray(8), 0)
+		bitD         = bd.New(8, 0)
 		checkBit     = []bool{false, true, false}
 		expectedBits = []bool{true, false, false, true, false, false}
 	)
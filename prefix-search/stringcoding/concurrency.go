@@ -0,0 +1,109 @@
+package stringcoding
+
+import "sync"
+
+// guard synchronises the goroutines sharing a LPRC or a PSRC: the methods that only
+// read the structure hold the read lock, so they run concurrently, while the ones
+// that change it, such as Insert, Append, Delete and Compact, hold the write lock.
+type guard struct {
+	mu sync.RWMutex
+	// version is increased by each method changing the structure, so that
+	// an Iterator can tell if the structure has been changed since its creation.
+	version uint64
+	// deletedIndexMu serialises the construction of the rank/select directory on
+	// the deleted strings, which is built lazily by the methods reading the structure.
+	deletedIndexMu sync.Mutex
+}
+
+func newGuard() *guard {
+	return &guard{}
+}
+
+// lock acquires the write lock, telling the iterators that the structure is changing.
+func (g *guard) lock() {
+	g.mu.Lock()
+	g.version++
+}
+
+func (g *guard) unlock() {
+	g.mu.Unlock()
+}
+
+func (g *guard) rlock() {
+	g.mu.RLock()
+}
+
+func (g *guard) runlock() {
+	g.mu.RUnlock()
+}
+
+// replace replaces the content of lprc with the one of other, but the guard:
+// it is read by the goroutines waiting for the lock, so it must not be written.
+func (lprc *LPRC) replace(other *LPRC) {
+	lprc.coding = other.coding
+	lprc.Epsilon = other.Epsilon
+	lprc.c = other.c
+	lprc.latestCompressedBitWritten = other.latestCompressedBitWritten
+	lprc.strings = other.strings
+	lprc.stringsCount = other.stringsCount
+	lprc.isUncompressed = other.isUncompressed
+	lprc.deleted = other.deleted
+	lprc.deletedCount = other.deletedCount
+	lprc.options = other.options
+	lprc.delta = other.delta
+	lprc.segments = other.segments
+}
+
+// replace replaces the content of psrc with the one of other, but the guard.
+func (psrc *PSRC) replace(other *PSRC) {
+	psrc.coding = other.coding
+	psrc.Epsilon = other.Epsilon
+	psrc.c = other.c
+	psrc.latestCompressedBitWritten = other.latestCompressedBitWritten
+	psrc.strings = other.strings
+	psrc.stringsCount = other.stringsCount
+	psrc.isUncompressed = other.isUncompressed
+	psrc.isStoredSuffix = other.isStoredSuffix
+	psrc.deleted = other.deleted
+	psrc.deletedCount = other.deletedCount
+	psrc.options = other.options
+	psrc.hashes = other.hashes
+}
+
+// syncIterator lets an Iterator be used while other goroutines use the structure:
+// each call to Next holds the read lock. Once the structure has been changed, Next
+// returns false and Err returns ErrConcurrentModification.
+type syncIterator struct {
+	it      Iterator
+	guard   *guard
+	version uint64
+	err     error
+}
+
+// newSyncIterator returns a syncIterator over it, which must have been created
+// while holding the read lock of g.
+func newSyncIterator(it Iterator, g *guard) *syncIterator {
+	return &syncIterator{it: it, guard: g, version: g.version}
+}
+
+// Next returns the next string and true, or false if there are no more strings.
+func (it *syncIterator) Next() (string, bool) {
+	if it.err != nil {
+		return "", false
+	}
+	it.guard.rlock()
+	defer it.guard.runlock()
+	if it.guard.version != it.version {
+		it.err = ErrConcurrentModification
+		return "", false
+	}
+	return it.it.Next()
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *syncIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err()
+}
@@ -0,0 +1,204 @@
+package stringcoding
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const goroutines = 8
+
+func TestLPRC_ConcurrentReads(t *testing.T) {
+	var (
+		words    = randomWords(800, "abcd", 31)
+		prefixes = append(randomWords(20, "abcd", 32), "")
+		lprc     = newLPRC(t, append([]string{}, words...), 1)
+		live     []string
+		wg       sync.WaitGroup
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for i, s := range words {
+		if i%5 == 0 { // the directory of the deleted strings is built by the readers
+			if _, err := lprc.Delete(s); err != nil {
+				t.Fatalf("LPRC.Delete(%q) error = %v", s, err)
+			}
+		} else {
+			live = append(live, s)
+		}
+	}
+	sorted := filterPrefix(live, "")
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := range prefixes {
+				prefix := prefixes[(i+g)%len(prefixes)] // the goroutines start from different prefixes
+				want := filterPrefix(live, prefix)
+				if got, err := lprc.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("LPRC.FullPrefixSearch(%q) = %v, %v, want %v", prefix, got, err, want)
+				}
+				if got, err := lprc.CountPrefix(prefix); err != nil || got != uint64(len(want)) {
+					t.Errorf("LPRC.CountPrefix(%q) = %d, %v, want %d", prefix, got, err, len(want))
+				}
+				if got, err := collect(lprc.PrefixIterator(prefix), 0, -1); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("LPRC.PrefixIterator(%q) = %v, %v, want %v", prefix, got, err, want)
+				}
+				if len(want) == 0 {
+					continue
+				}
+				wantRank := uint64(sort.SearchStrings(sorted, want[0]))
+				if rank, found := lprc.IndexOf(want[0]); !found || rank != wantRank {
+					t.Errorf("LPRC.IndexOf(%q) = %d, %v, want %d, true", want[0], rank, found, wantRank)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestLPRC_ConcurrentReadWrite(t *testing.T) {
+	var (
+		words    = randomWords(1200, "abcd", 33)
+		prefixes = append(randomWords(10, "abcd", 34), "")
+		lprc     = newLPRC(t, append([]string{}, words[:600]...), 1, WithDeltaThreshold(50))
+		wg       sync.WaitGroup
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(2)
+		go func(g int) { // each writer inserts and deletes its own strings
+			defer wg.Done()
+			for i := 600 + g; i < len(words); i += goroutines {
+				if err := lprc.Insert(words[i]); err != nil {
+					t.Errorf("LPRC.Insert(%q) error = %v", words[i], err)
+				}
+			}
+			for i := g; i < 600; i += 2 * goroutines {
+				if _, err := lprc.Delete(words[i]); err != nil {
+					t.Errorf("LPRC.Delete(%q) error = %v", words[i], err)
+				}
+			}
+		}(g)
+		go func(g int) { // the readers always see sorted results starting with the prefix
+			defer wg.Done()
+			for _, prefix := range prefixes {
+				got, err := lprc.FullPrefixSearch(prefix)
+				if err != nil {
+					t.Errorf("LPRC.FullPrefixSearch(%q) error = %v", prefix, err)
+					continue
+				}
+				if !sort.StringsAreSorted(got) {
+					t.Errorf("LPRC.FullPrefixSearch(%q) is not sorted", prefix)
+				}
+				for _, s := range got {
+					if !strings.HasPrefix(s, prefix) {
+						t.Errorf("LPRC.FullPrefixSearch(%q) returned %q", prefix, s)
+					}
+				}
+				it := lprc.PrefixIterator(prefix)
+				for _, ok := it.Next(); ok; _, ok = it.Next() {
+				}
+				if err := it.Err(); err != nil && err != ErrConcurrentModification {
+					t.Errorf("LPRC.PrefixIterator(%q) error = %v", prefix, err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var live []string
+	for i, s := range words {
+		if i >= 600 || i%(2*goroutines) >= goroutines {
+			live = append(live, s)
+		}
+	}
+	if got, err := lprc.FullPrefixSearch(""); err != nil || !reflect.DeepEqual(got, filterPrefix(live, "")) {
+		t.Errorf("LPRC.FullPrefixSearch() after the writers = %d strings, %v, want %d", len(got), err, len(live))
+	}
+}
+
+func TestPSRC_ConcurrentAppend(t *testing.T) {
+	var (
+		words = randomWords(400, "abcd", 35)
+		psrc  = newPSRC(t, append([]string{}, words[:100]...), 1, WithMultiset(true))
+		wg    sync.WaitGroup
+	)
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 100 + g; i < len(words); i += goroutines {
+				id, err := psrc.Append(words[i])
+				if err != nil {
+					t.Errorf("PSRC.Append(%q) error = %v", words[i], err)
+					continue
+				}
+				if got, err := psrc.Get(id); err != nil || got != words[i] {
+					t.Errorf("PSRC.Get(%d) = %q, %v, want %q", id, got, err, words[i])
+				}
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if _, err := psrc.FullPrefixSearch("a"); err != nil {
+					t.Errorf("PSRC.FullPrefixSearch() error = %v", err)
+				}
+				if _, err := psrc.Get(uint64(i)); err != nil {
+					t.Errorf("PSRC.Get(%d) error = %v", i, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := psrc.Len(); got != uint64(len(words)) {
+		t.Errorf("PSRC.Len() = %d, want %d", got, len(words))
+	}
+	got, err := psrc.FullPrefixSearch("")
+	sort.Strings(got)
+	if want := filterPrefix(words, ""); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PSRC.FullPrefixSearch() = %d strings, %v, want %d", len(got), err, len(want))
+	}
+}
+
+func TestIterator_ConcurrentModification(t *testing.T) {
+	var (
+		lprc = newLPRC(t, []string{"casotto", "cat", "cena"}, 1)
+		psrc = newPSRC(t, []string{"casotto", "cat", "cena"}, 1)
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	its := map[string]Iterator{"LPRC": lprc.PrefixIterator("c"), "PSRC": psrc.PrefixIterator("c")}
+	for name, it := range its {
+		if s, ok := it.Next(); !ok || s != "casotto" {
+			t.Errorf("%s iterator Next() = %q, %v, want casotto", name, s, ok)
+		}
+	}
+	if err := lprc.Insert("ciao"); err != nil {
+		t.Fatalf("LPRC.Insert() error = %v", err)
+	}
+	if _, err := psrc.Append("ciao"); err != nil {
+		t.Fatalf("PSRC.Append() error = %v", err)
+	}
+	for name, it := range its {
+		if s, ok := it.Next(); ok {
+			t.Errorf("%s iterator Next() after a change = %q, want false", name, s)
+		}
+		if err := it.Err(); err != ErrConcurrentModification {
+			t.Errorf("%s iterator Err() = %v, want %v", name, err, ErrConcurrentModification)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package stringcoding
+
+import (
+	"context"
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// canceled returns ctx.Err() if ctx is done, where a nil ctx is never done.
+// It is checked before each string is coded or decoded: since it does not block,
+// it costs far less than the string.
+func canceled(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// PopulateContext is Populate, but it stops once ctx is done, returning ctx.Err().
+// The LPRC is then left as it was before, so that it can be populated again.
+func (lprc *LPRC) PopulateContext(ctx context.Context) error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	return lprc.populateContext(ctx)
+}
+
+// unpopulate takes the LPRC back to the state it had before it started to be populated.
+func (lprc *LPRC) unpopulate() {
+	count := uint64(len(lprc.strings))
+	lprc.coding = NewWithCoder(lprc.strings, lprc.options.lengthsCoder)
+	lprc.isUncompressed = bd.New(count, count)
+	lprc.latestCompressedBitWritten = 0
+}
+
+// PopulateContext is Populate, but it stops once ctx is done, returning ctx.Err().
+// The PSRC is then left as it was before, so that it can be populated again.
+func (psrc *PSRC) PopulateContext(ctx context.Context) error {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	return psrc.populateContext(ctx)
+}
+
+// unpopulate takes the PSRC back to the state it had before it started to be populated.
+func (psrc *PSRC) unpopulate() {
+	count := uint64(len(psrc.strings))
+	psrc.coding = NewWithCoder(psrc.strings, psrc.options.lengthsCoder)
+	psrc.isUncompressed = bd.New(count, 0)
+	psrc.isStoredSuffix = bd.New(count, 0)
+	psrc.latestCompressedBitWritten = 0
+}
@@ -0,0 +1,150 @@
+package stringcoding
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// countdownContext is canceled once its Done has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (ctx *countdownContext) Done() <-chan struct{} {
+	if ctx.n == 0 {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	ctx.n--
+	return nil // it blocks forever
+}
+
+func (ctx *countdownContext) Err() error {
+	if ctx.n == 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestFullPrefixSearchContext(t *testing.T) {
+	words := randomWords(1000, "abc", 51)
+	for name, impl := range populatedImpls(t, words) {
+		for _, prefix := range []string{"", "a", "bca"} {
+			want, _ := impl.FullPrefixSearch(prefix)
+			got, err := impl.FullPrefixSearchContext(context.Background(), prefix)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s.FullPrefixSearchContext(%q) = %d strings, %v, want %d", name, prefix, len(got), err,
+					len(want))
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if got, err := impl.FullPrefixSearchContext(ctx, ""); err != context.Canceled {
+			t.Errorf("%s.FullPrefixSearchContext() with a canceled context = %d strings, %v, want %v", name,
+				len(got), err, context.Canceled)
+		}
+
+		countdown := &countdownContext{context.Background(), 500}
+		if _, err := impl.FullPrefixSearchContext(countdown, ""); err != context.Canceled {
+			t.Errorf("%s.FullPrefixSearchContext() canceled while searching error = %v, want %v", name, err,
+				context.Canceled)
+		}
+	}
+}
+
+func TestPrefixIteratorContext(t *testing.T) {
+	words := randomWords(1000, "abc", 52)
+	for name, impl := range populatedImpls(t, words) {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := impl.PrefixIteratorContext(ctx, "")
+		for i := 0; i < 10; i++ {
+			if _, ok := it.Next(); !ok {
+				t.Fatalf("%s iterator Next() = false, %v", name, it.Err())
+			}
+		}
+		cancel()
+		if _, ok := it.Next(); ok || it.Err() != context.Canceled {
+			t.Errorf("%s iterator after cancel Next() = %v, Err() = %v, want false, %v", name, ok, it.Err(),
+				context.Canceled)
+		}
+	}
+}
+
+func TestPopulateContext(t *testing.T) {
+	words := randomWords(1000, "abc", 53)
+	impls := map[string]PrefixSearch{}
+	lprc := newLPRC(t, append([]string{}, words...), 1)
+	impls["LPRC"] = &lprc
+	psrc := newPSRC(t, append([]string{}, words...), 1)
+	impls["PSRC"] = &psrc
+	for name, impl := range impls {
+		if err := impl.PopulateContext(&countdownContext{context.Background(), 500}); err != context.Canceled {
+			t.Errorf("%s.PopulateContext() error = %v, want %v", name, err, context.Canceled)
+		}
+		// the structure can be populated again once the coding has been interrupted
+		if err := impl.PopulateContext(context.Background()); err != nil {
+			t.Fatalf("%s.PopulateContext() error = %v", name, err)
+		}
+		got, err := impl.FullPrefixSearch("ab")
+		if name == "PSRC" {
+			got = filterPrefix(got, "")
+		}
+		if want := filterPrefix(words, "ab"); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s.FullPrefixSearch() = %v, %v, want %v", name, got, err, want)
+		}
+	}
+}
+
+// failingCoder is an EliasGammaCoder whose Encode fails once it has encoded n values.
+type failingCoder struct {
+	EliasGammaCoder
+	n *int
+}
+
+func (c failingCoder) Encode(dst *bd.BitData, n uint64) error {
+	if *c.n == 0 {
+		return ErrValueTooLarge
+	}
+	*c.n--
+	return c.EliasGammaCoder.Encode(dst, n)
+}
+
+func TestPopulateError(t *testing.T) {
+	var (
+		words   = randomWords(1000, "abc", 54)
+		encodes = [2]int{300, 300}
+		lprc    = newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(failingCoder{n: &encodes[0]}))
+		psrc    = newPSRC(t, append([]string{}, words...), 1, WithLengthsCoder(failingCoder{n: &encodes[1]}))
+		impls   = map[string]PrefixSearch{"LPRC": &lprc, "PSRC": &psrc}
+	)
+	for name, impl := range impls {
+		err := impl.Populate()
+		if stringErr, ok := err.(*StringError); !ok || stringErr.Unwrap() != ErrValueTooLarge {
+			t.Errorf("%s.Populate() error = %v, want a *StringError wrapping %v", name, err, ErrValueTooLarge)
+		}
+	}
+	if lprc.coding.Strings.Len != 0 || lprc.latestCompressedBitWritten != 0 ||
+		psrc.coding.Strings.Len != 0 || psrc.isUncompressed.Len != 0 {
+		t.Errorf("Populate() should leave the structure as it was before when a string cannot be coded")
+	}
+	// the structure can be populated again once the coder does not fail
+	encodes = [2]int{len(words), len(words)}
+	for name, impl := range impls {
+		if err := impl.Populate(); err != nil {
+			t.Fatalf("%s.Populate() error = %v", name, err)
+		}
+		got, err := impl.FullPrefixSearch("ab")
+		if name == "PSRC" {
+			got = filterPrefix(got, "")
+		}
+		if want := filterPrefix(words, "ab"); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s.FullPrefixSearch() = %v, %v, want %v", name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package stringcoding
+
+// CountPrefix returns the number of strings in the LPRC starting with prefix.
+// The coded strings are counted from the range found by the binary search,
+// so they are not decoded, while the strings inserted with Insert are counted too.
+func (lprc *LPRC) CountPrefix(prefix string) (uint64, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	count := uint64(len(lprc.deltaPrefixSearch(prefix)))
+	for _, part := range lprc.parts() {
+		l, r, err := part.prefixRange(prefix)
+		if err != nil {
+			return uint64(0), err
+		}
+		if l > r { // no coded string starts with prefix
+			continue
+		}
+		deletedBeforeL, err := part.deletedBefore(l)
+		if err != nil {
+			return uint64(0), err
+		}
+		deletedUpToR, err := part.deletedBefore(r + 1)
+		if err != nil {
+			return uint64(0), err
+		}
+		count += (r - l + 1) - (deletedUpToR - deletedBeforeL)
+	}
+	return count, nil
+}
+
+// CountPrefix returns the number of strings in the PSRC starting with prefix.
+// The strings are decoded one after the other only once, as done by PrefixIterator.
+func (psrc *PSRC) CountPrefix(prefix string) (uint64, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	var (
+		count = uint64(0)
+		it    = psrc.prefixIterator(prefix)
+	)
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+		count++
+	}
+	return count, it.Err()
+}
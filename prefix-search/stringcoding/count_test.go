@@ -0,0 +1,58 @@
+package stringcoding
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountPrefix(t *testing.T) {
+	var (
+		words    = randomWords(600, "abcd", 17)
+		prefixes = append(randomWords(40, "abcd", 18), "", "e")
+		lprc     = newLPRC(t, append([]string{}, words[:550]...), 1)
+		psrc     = newPSRC(t, append([]string{}, words[:300]...), 1)
+		live     = map[string]bool{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for _, s := range words[550:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for i, s := range words {
+		if i%3 == 0 {
+			if _, err := lprc.Delete(s); err != nil {
+				t.Fatalf("LPRC.Delete(%q) error = %v", s, err)
+			}
+			if i < 300 {
+				if _, err := psrc.Delete(s); err != nil {
+					t.Fatalf("PSRC.Delete(%q) error = %v", s, err)
+				}
+			}
+		} else {
+			live[s] = true
+		}
+	}
+	count := func(words []string, prefix string) uint64 {
+		n := uint64(0)
+		for _, s := range words {
+			if live[s] && strings.HasPrefix(s, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+	for _, prefix := range prefixes {
+		if got, err := lprc.CountPrefix(prefix); err != nil || got != count(words, prefix) {
+			t.Errorf("LPRC.CountPrefix(%q) = %v, %v, want %v", prefix, got, err, count(words, prefix))
+		}
+		if got, err := psrc.CountPrefix(prefix); err != nil || got != count(words[:300], prefix) {
+			t.Errorf("PSRC.CountPrefix(%q) = %v, %v, want %v", prefix, got, err, count(words[:300], prefix))
+		}
+	}
+}
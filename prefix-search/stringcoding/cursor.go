@@ -0,0 +1,186 @@
+package stringcoding
+
+import (
+	"strings"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// lprcCursor decodes the strings of a LPRC one after the other starting
+// from an uncompressed one: each string is rebuilt from the previous one,
+// so a run of consecutive strings is decoded only once.
+type lprcCursor struct {
+	lprc *LPRC
+	// index of the string held by the cursor.
+	index uint64
+	// current is the string held by the cursor, null char included,
+	// as a sequence of bits.
+	current *bd.BitData
+	// lengths decodes the code of the next string, it is nil
+	// if the cursor is on the last string.
+	lengths *lengthsReader
+}
+
+// newCursor returns a cursor on the string u, which must be stored uncompressed.
+func (lprc *LPRC) newCursor(u uint64) (*lprcCursor, error) {
+	start, length, err := lprc.coding.getChunk(u, lprc.stringsCount)
+	if err != nil {
+		return nil, err
+	}
+	current := bd.New(length, 0)
+	if err := current.AppendRange(lprc.coding.Strings, start, length); err != nil {
+		return nil, err
+	}
+	cursor := &lprcCursor{lprc: lprc, index: u, current: current}
+	if u+1 < lprc.stringsCount {
+		if cursor.lengths, err = lprc.coding.newLengthsReader(u + 1); err != nil {
+			return nil, err
+		}
+	}
+	return cursor, nil
+}
+
+// cursorAt returns a cursor on the string u, decoding the strings from
+// the uncompressed one before u.
+func (lprc *LPRC) cursorAt(u uint64) (*lprcCursor, error) {
+	anchor, err := lprc.getAnchor(u)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := lprc.newCursor(anchor)
+	if err != nil {
+		return nil, err
+	}
+	for cursor.index < u {
+		if err := cursor.next(); err != nil {
+			return nil, err
+		}
+	}
+	return cursor, nil
+}
+
+// next moves the cursor on the following string.
+func (cursor *lprcCursor) next() error {
+	var (
+		lprc  = cursor.lprc
+		index = cursor.index + 1
+	)
+	start, length, err := lprc.coding.getChunk(index, lprc.stringsCount)
+	if err != nil {
+		return err
+	}
+	if cursor.lengths == nil { // there are no more strings
+		return bd.ErrIndexOutOfBound
+	}
+	li, err := cursor.lengths.next() // li is the number of bits to remove in the previous string
+	if err != nil {
+		return err
+	}
+	if li > cursor.current.Len { // the previous string is shorter than that: Lengths is corrupted
+		return bd.ErrIndexOutOfBound
+	}
+	var (
+		previous = cursor.current
+		ni       = previous.Len - li // length of the common prefix (0 if the string is uncompressed)
+		current  = bd.New(ni+length, 0)
+	)
+	// the suffix stored in Strings are the least significant bits...
+	if err := current.AppendRange(lprc.coding.Strings, start, length); err != nil {
+		return err
+	}
+	// ...while the common prefix is made of the most significant bits of the previous string
+	if err := current.AppendRange(previous, previous.Len-ni, ni); err != nil {
+		return err
+	}
+	cursor.index = index
+	cursor.current = current
+	return nil
+}
+
+// String returns the string held by the cursor.
+func (cursor *lprcCursor) String() (string, error) {
+	s, err := cursor.current.BitToString()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(s, "\x00"), nil
+}
+
+// comparePrefix compares the first len(prefix) bytes of the string held by
+// the cursor with prefix: it returns 0 if the string starts with prefix,
+// -1 if it comes before all the strings starting with prefix and +1 otherwise.
+func (cursor *lprcCursor) comparePrefix(prefix string) (int, error) {
+	s, err := cursor.String()
+	if err != nil {
+		return 0, err
+	}
+	if len(s) > len(prefix) {
+		s = s[:len(prefix)]
+	}
+	return strings.Compare(s, prefix), nil
+}
+
+// psrcCursor decodes the strings of a PSRC one after the other starting
+// from the first one: each string is rebuilt from the previous one.
+type psrcCursor struct {
+	psrc *PSRC
+	// index of the string held by the cursor.
+	index uint64
+	// current is the string held by the cursor, null chars included,
+	// as a sequence of bits.
+	current *bd.BitData
+	// lengths decodes the code of the next string, it is nil
+	// if the cursor is on the last string.
+	lengths *lengthsReader
+}
+
+// newCursor returns a cursor on the first string of the PSRC, which is
+// always stored uncompressed.
+func (psrc *PSRC) newCursor() (*psrcCursor, error) {
+	length, err := psrc.getLengthInStrings(0)
+	if err != nil {
+		return nil, err
+	}
+	current, err := psrc.storedPrefix(0, length)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &psrcCursor{psrc: psrc, current: current}
+	if psrc.stringsCount > 1 {
+		if cursor.lengths, err = psrc.coding.newLengthsReader(1); err != nil {
+			return nil, err
+		}
+	}
+	return cursor, nil
+}
+
+// next moves the cursor on the following string.
+func (cursor *psrcCursor) next() error {
+	if cursor.lengths == nil { // there are no more strings
+		return bd.ErrIndexOutOfBound
+	}
+	li, err := cursor.lengths.next() // li is the number of bits to remove in the previous string
+	if err != nil {
+		return err
+	}
+	index := cursor.index + 1
+	current, err := cursor.psrc.decodeNext(cursor.current, index, li)
+	if err != nil {
+		return err
+	}
+	cursor.index = index
+	cursor.current = current
+	if index+1 == cursor.psrc.stringsCount {
+		cursor.lengths = nil
+	}
+	return nil
+}
+
+// String returns the string held by the cursor.
+func (cursor *psrcCursor) String() (string, error) {
+	s, err := cursor.current.BitToString()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(s, "\x00"), "\x00"), nil
+}
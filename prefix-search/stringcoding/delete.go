@@ -0,0 +1,216 @@
+package stringcoding
+
+import (
+	"sort"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// Delete removes the string s from the LPRC and tells if it was there.
+// With WithMultiset, a single occurrence of s is removed.
+// A coded string is not removed from the data structures: it is marked as
+// deleted, so that it is skipped by the queries, until Compact codes again the LPRC.
+func (lprc *LPRC) Delete(s string) (bool, error) {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	if i := indexOfString(lprc.delta, s); i >= 0 { // it has not been coded yet
+		lprc.delta = append(lprc.delta[:i], lprc.delta[i+1:]...)
+		return true, nil
+	}
+	for _, part := range lprc.parts() {
+		u, live, err := part.liveIndexOf(s)
+		if err != nil {
+			return false, err
+		}
+		if live {
+			return true, part.deleteID(u)
+		}
+	}
+	return false, nil
+}
+
+// DeleteID marks the string having index id as deleted.
+// Indexes are the ids given by Get, so they change when the strings are
+// coded again by Flush or Compact.
+func (lprc *LPRC) DeleteID(id uint64) error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	return lprc.deleteID(id)
+}
+
+// deleteID is DeleteID without locking.
+func (lprc *LPRC) deleteID(id uint64) error {
+	part, u, err := lprc.partOf(id)
+	if err != nil {
+		return err
+	}
+	return markDeleted(part.deleted, &part.deletedCount, u)
+}
+
+// restore clears the mark of the deleted string having index u among the ones coded by the LPRC.
+func (lprc *LPRC) restore(u uint64) error {
+	isDeleted, err := lprc.deleted.GetBit(u)
+	if err != nil || !isDeleted {
+		return err
+	}
+	if err := lprc.deleted.ClearBit(u); err != nil {
+		return err
+	}
+	lprc.deletedCount--
+	return nil
+}
+
+// Compact codes again the LPRC without the deleted strings if the ratio of
+// deleted strings is greater than the threshold given by WithCompactThreshold,
+// and tells if it has been done. The strings coded by Flush in the segments and
+// the ones inserted with Insert are coded together with the others.
+func (lprc *LPRC) Compact() (bool, error) {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	if count, deletedCount := lprc.codedCount(); !mustCompact(deletedCount, count, lprc.options) {
+		return false, nil
+	}
+	return true, lprc.rebuild()
+}
+
+// Delete marks as deleted each string equal to s and tells if there was one.
+// The deleted strings keep their ids, so that the ids of the other strings
+// do not change, until Compact codes again the PSRC.
+func (psrc *PSRC) Delete(s string) (bool, error) {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	if len(s) == 0 && !psrc.options.multiset {
+		return false, ErrEmptyString
+	}
+	if err := psrc.populateIfNeeded(); err != nil {
+		return false, err
+	}
+	found := false
+	for i := uint64(0); i < psrc.stringsCount; i++ {
+		isDeleted, err := psrc.deleted.GetBit(i)
+		if err != nil {
+			return found, err
+		}
+		if isDeleted {
+			continue
+		}
+		stringI, err := psrc.get(i)
+		if err != nil {
+			return found, err
+		}
+		if stringI == s {
+			if err := psrc.deleteID(i); err != nil {
+				return found, err
+			}
+			found = true
+		}
+	}
+	return found, nil
+}
+
+// DeleteID marks the string having the given id as deleted.
+func (psrc *PSRC) DeleteID(id uint64) error {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	return psrc.deleteID(id)
+}
+
+// deleteID is DeleteID without locking.
+func (psrc *PSRC) deleteID(id uint64) error {
+	if err := psrc.populateIfNeeded(); err != nil {
+		return err
+	}
+	return markDeleted(psrc.deleted, &psrc.deletedCount, id)
+}
+
+// Compact codes again the PSRC without the deleted strings if the ratio of
+// deleted strings is greater than the threshold given by WithCompactThreshold,
+// and tells if it has been done.
+// The strings that have not been deleted keep their order, but their ids change.
+func (psrc *PSRC) Compact() (bool, error) {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	if !mustCompact(psrc.deletedCount, psrc.stringsCount, psrc.options) {
+		return false, nil
+	}
+	live := make([]string, 0, psrc.stringsCount-psrc.deletedCount)
+	for i := uint64(0); i < psrc.stringsCount; i++ {
+		isDeleted, err := psrc.deleted.GetBit(i)
+		if err != nil {
+			return false, err
+		}
+		if isDeleted {
+			continue
+		}
+		s, err := psrc.get(i)
+		if err != nil {
+			return false, err
+		}
+		live = append(live, s)
+	}
+	compacted, err := NewPSRC(live, psrc.Epsilon, withOptions(psrc.options))
+	if err != nil {
+		return false, err
+	}
+	if err := compacted.Populate(); err != nil {
+		return false, err
+	}
+	psrc.replace(&compacted)
+	return true, nil
+}
+
+// populateIfNeeded populates the PSRC if it has not been done yet.
+func (psrc *PSRC) populateIfNeeded() error {
+	if psrc.strings == nil {
+		return nil
+	}
+	return psrc.populate()
+}
+
+// checkDeleted returns ErrDeletedString if the u-th string has been deleted.
+func checkDeleted(deleted *bd.BitData, u uint64) error {
+	isDeleted, err := deleted.GetBit(u)
+	if err != nil {
+		return err
+	}
+	if isDeleted {
+		return ErrDeletedString
+	}
+	return nil
+}
+
+// markDeleted sets the bit of the u-th string in deleted, counting it in deletedCount
+// unless it was already set.
+func markDeleted(deleted *bd.BitData, deletedCount *uint64, u uint64) error {
+	isDeleted, err := deleted.GetBit(u)
+	if err != nil || isDeleted {
+		return err
+	}
+	if err := deleted.SetBit(u); err != nil {
+		return err
+	}
+	*deletedCount++
+	return nil
+}
+
+// countDeleted returns the number of strings marked in deleted.
+func countDeleted(deleted *bd.BitData) (uint64, error) {
+	if deleted.Len == 0 {
+		return uint64(0), nil
+	}
+	return deleted.Rank1(deleted.Len)
+}
+
+// mustCompact tells if the ratio of deleted strings is above the compact threshold.
+func mustCompact(deletedCount uint64, stringsCount uint64, o options) bool {
+	return deletedCount > 0 && float64(deletedCount) > o.compactThreshold*float64(stringsCount)
+}
+
+// indexOfString returns the index of s in the sorted strings, or -1 if s is not there.
+func indexOfString(strings []string, s string) int {
+	i := sort.SearchStrings(strings, s)
+	if i < len(strings) && strings[i] == s {
+		return i
+	}
+	return -1
+}
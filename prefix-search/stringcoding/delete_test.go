@@ -0,0 +1,199 @@
+package stringcoding
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLPRC_Delete(t *testing.T) {
+	var (
+		words    = randomWords(600, "abcd", 7)
+		prefixes = append(randomWords(30, "abcd", 8), "")
+		lprc     = newLPRC(t, append([]string{}, words[:500]...), 1, WithDeltaThreshold(1000),
+			WithCompactThreshold(0.5))
+		live = map[string]bool{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for _, s := range words[500:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for i, s := range words {
+		if i%3 == 0 { // coded and inserted strings are deleted
+			if ok, err := lprc.Delete(s); err != nil || !ok {
+				t.Fatalf("LPRC.Delete(%q) = %v, %v, want true", s, ok, err)
+			}
+		} else {
+			live[s] = true
+		}
+	}
+	if ok, err := lprc.Delete(words[0]); err != nil || ok {
+		t.Errorf("LPRC.Delete() of a deleted string = %v, %v, want false", ok, err)
+	}
+	if ok, err := lprc.Delete("zzz"); err != nil || ok {
+		t.Errorf("LPRC.Delete() of a missing string = %v, %v, want false", ok, err)
+	}
+	if got := lprc.Len(); got != uint64(len(live)) {
+		t.Errorf("LPRC.Len() = %d, want %d", got, len(live))
+	}
+	u, _, err := lprc.codedIndexOf(words[0])
+	if err != nil {
+		t.Fatalf("LPRC.codedIndexOf() error = %v", err)
+	}
+	if _, err := lprc.Retrieval(u, 8); err != ErrDeletedString {
+		t.Errorf("LPRC.Retrieval() error = %v, want %v", err, ErrDeletedString)
+	}
+
+	check := func(step string) {
+		var liveWords []string
+		for _, s := range words {
+			if live[s] {
+				liveWords = append(liveWords, s)
+			}
+		}
+		for _, prefix := range prefixes {
+			got, err := lprc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) error = %v", step, prefix, err)
+				continue
+			}
+			if want := filterPrefix(liveWords, prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) = %v, want %v", step, prefix, got, want)
+			}
+		}
+	}
+	check("deleted")
+
+	if ok, err := lprc.Compact(); err != nil || ok { // only a third of the strings has been deleted
+		t.Errorf("LPRC.Compact() = %v, %v, want false", ok, err)
+	}
+	for i := 3; i < 30; i += 3 { // deleted strings can be inserted again
+		s := words[i]
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+		live[s] = true
+	}
+	check("inserted again")
+
+	lprc.options.compactThreshold = 0.1
+	if ok, err := lprc.Compact(); err != nil || !ok {
+		t.Fatalf("LPRC.Compact() = %v, %v, want true", ok, err)
+	}
+	if lprc.deletedCount != 0 || lprc.stringsCount != uint64(len(live)) || len(lprc.delta) != 0 {
+		t.Errorf("LPRC.Compact() kept %d deleted strings out of %d", lprc.deletedCount, lprc.stringsCount)
+	}
+	check("compacted")
+}
+
+func TestLPRC_DeleteBeforePopulate(t *testing.T) {
+	lprc := newLPRC(t, []string{"zebra", "casotto", "delfino"}, 1)
+	if ok, err := lprc.Delete("delfino"); err != nil || !ok {
+		t.Fatalf("LPRC.Delete() = %v, %v, want true", ok, err)
+	}
+	if err := lprc.DeleteID(3); err == nil {
+		t.Errorf("LPRC.DeleteID() should fail for a missing id")
+	}
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	want := []string{"casotto", "zebra"}
+	if got, err := lprc.FullPrefixSearch(""); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestPSRC_Delete(t *testing.T) {
+	var (
+		words = []string{"caso", "cat", "cena", "cat", "delfino", "dente"}
+		psrc  = newPSRC(t, append([]string{}, words...), 1, WithCompactThreshold(0.4), WithMultiset(true))
+	)
+	if ok, err := psrc.Delete("cat"); err != nil || !ok {
+		t.Fatalf("PSRC.Delete() = %v, %v, want true", ok, err)
+	}
+	if ok, err := psrc.Delete("ca"); err != nil || ok {
+		t.Errorf("PSRC.Delete() of a prefix = %v, %v, want false", ok, err)
+	}
+	if ok, err := psrc.Delete(""); err != nil || ok {
+		t.Errorf("PSRC.Delete(\"\") = %v, %v, want false", ok, err)
+	}
+	for _, u := range []uint64{1, 3} {
+		if _, err := psrc.Retrieval(u, 8); err != ErrDeletedString {
+			t.Errorf("PSRC.Retrieval(%d) error = %v, want %v", u, err, ErrDeletedString)
+		}
+	}
+	if got, want := psrc.Len(), uint64(4); got != want {
+		t.Errorf("PSRC.Len() = %d, want %d", got, want)
+	}
+	if ok, err := psrc.Compact(); err != nil || ok {
+		t.Errorf("PSRC.Compact() = %v, %v, want false", ok, err)
+	}
+	if err := psrc.DeleteID(5); err != nil {
+		t.Fatalf("PSRC.DeleteID() error = %v", err)
+	}
+	if err := psrc.DeleteID(6); err == nil {
+		t.Errorf("PSRC.DeleteID() should fail for a missing id")
+	}
+
+	want := []string{"caso", "cena"}
+	if got, err := psrc.FullPrefixSearch("c"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PSRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+	if ok, err := psrc.Compact(); err != nil || !ok {
+		t.Fatalf("PSRC.Compact() = %v, %v, want true", ok, err)
+	}
+	for u, want := range []string{"caso", "cena", "delfino"} {
+		if got, err := psrc.Retrieval(uint64(u), uint64(len(want)*8)); err != nil || got != want {
+			t.Errorf("PSRC.Retrieval(%d) = %v, %v, want %v", u, got, err, want)
+		}
+	}
+	if got, err := psrc.FullPrefixSearch("c"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PSRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestDelete_WriteToReadFrom(t *testing.T) {
+	var (
+		lprc       = newLPRC(t, []string{"casotto", "delfino", "dente", "zebra"}, 1)
+		psrc       = newPSRC(t, []string{"casotto", "delfino", "dente", "zebra"}, 1)
+		lprcBuffer bytes.Buffer
+		psrcBuffer bytes.Buffer
+		loadedLPRC LPRC
+		loadedPSRC PSRC
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if _, err := lprc.Delete("delfino"); err != nil {
+		t.Fatalf("LPRC.Delete() error = %v", err)
+	}
+	if _, err := psrc.Delete("delfino"); err != nil {
+		t.Fatalf("PSRC.Delete() error = %v", err)
+	}
+	if _, err := lprc.WriteTo(&lprcBuffer); err != nil {
+		t.Fatalf("LPRC.WriteTo() error = %v", err)
+	}
+	if _, err := psrc.WriteTo(&psrcBuffer); err != nil {
+		t.Fatalf("PSRC.WriteTo() error = %v", err)
+	}
+	if _, err := loadedLPRC.ReadFrom(&lprcBuffer); err != nil {
+		t.Fatalf("LPRC.ReadFrom() error = %v", err)
+	}
+	if _, err := loadedPSRC.ReadFrom(&psrcBuffer); err != nil {
+		t.Fatalf("PSRC.ReadFrom() error = %v", err)
+	}
+	want := []string{"dente"}
+	if got, err := loadedLPRC.FullPrefixSearch("de"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := loadedPSRC.FullPrefixSearch("de"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PSRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+	if loadedLPRC.Len() != 3 || loadedPSRC.Len() != 3 {
+		t.Errorf("Len() after ReadFrom = %d, %d, want 3", loadedLPRC.Len(), loadedPSRC.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package stringcoding
+
+import (
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+	"math/bits"
+)
+
+// eliasFano is the Elias-Fano representation of a nondecreasing sequence of values.
+// The lowWidth least significant bits of each value are written as they are in low,
+// while the remaining high bits are written in high as the difference from the high bits
+// of the previous value, in unary: as many 0s, followed by a 1.
+// Since high has a 1 for each value and at most u >> lowWidth 0s, where u is the last value,
+// with lowWidth = log_2(u/n) each one of the n values takes about 2 + log_2(u/n) bits.
+type eliasFano struct {
+	low  *bd.BitData
+	high *bd.BitData
+	// lowWidth is the number of bits of each value written in low.
+	lowWidth uint64
+	// count is the number of values.
+	count uint64
+	// lastHigh contains the high bits of the last value.
+	lastHigh uint64
+}
+
+// newEliasFano returns an empty eliasFano for about n values not greater than u.
+// Other values can be appended, as long as they are not less than the last one,
+// but the bits written in low are chosen on n and u.
+func newEliasFano(n uint64, u uint64) *eliasFano {
+	lowWidth := uint64(0)
+	if n > 0 && u > n {
+		lowWidth = uint64(bits.Len64(u/n)) - 1 // |_log_2 (u/n) _|
+	}
+	return &eliasFano{
+		low:      bd.New(n*lowWidth, 0),
+		high:     bd.New(n+u>>lowWidth, 0),
+		lowWidth: lowWidth,
+	}
+}
+
+// append appends the value v, that must not be less than the last one.
+func (ef *eliasFano) append(v uint64) error {
+	high := v >> ef.lowWidth
+	if high < ef.lastHigh {
+		return ErrDecreasingValue
+	}
+	if err := appendZeros(ef.high, high-ef.lastHigh); err != nil {
+		return err
+	}
+	if err := ef.high.AppendBit(true); err != nil {
+		return err
+	}
+	if err := ef.low.AppendWord(v, ef.lowWidth); err != nil { // only the lowWidth least significant bits
+		return err
+	}
+	ef.lastHigh = high
+	ef.count++
+	return nil
+}
+
+// get returns the k-th value, starting from 0.
+// With the rank/select directory on high (see buildIndex), it takes constant time.
+func (ef *eliasFano) get(k uint64) (uint64, error) {
+	if k >= ef.count {
+		return uint64(0), bd.ErrIndexOutOfBound
+	}
+	position, err := ef.high.Select1(k + 1) // the 1 of the k-th value follows its high bits 0s
+	if err != nil {
+		return uint64(0), err
+	}
+	low, err := ef.low.GetBits(k*ef.lowWidth, ef.lowWidth)
+	if err != nil {
+		return uint64(0), err
+	}
+	return (position-k)<<ef.lowWidth | low, nil
+}
+
+// buildIndex builds the rank/select directory on high, that is kept up to date by append.
+func (ef *eliasFano) buildIndex() error {
+	return ef.high.BuildIndex()
+}
+
+// load completes an eliasFano whose low and high have been read: it returns
+// ErrInvalidFormat if they are not the Elias-Fano representation of count values.
+// The rank/select directory is not built.
+func (ef *eliasFano) load(count uint64) error {
+	ones, err := ef.high.PopCount(0, ef.high.Len)
+	if err != nil || ones != count {
+		return ErrInvalidFormat
+	}
+	ef.count = count
+	ef.lowWidth = uint64(0)
+	if count > 0 {
+		if ef.low.Len%count != 0 || ef.low.Len/count > 64 {
+			return ErrInvalidFormat
+		}
+		ef.lowWidth = ef.low.Len / count
+		last, err := ef.high.PrevOne(ef.high.Len - 1)
+		if err != nil || last != ef.high.Len-1 { // high ends with the 1 of the last value
+			return ErrInvalidFormat
+		}
+		ef.lastHigh = last - (count - 1)
+	} else if ef.low.Len > 0 || ef.high.Len > 0 {
+		return ErrInvalidFormat
+	}
+	return nil
+}
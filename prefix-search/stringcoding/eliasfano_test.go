@@ -0,0 +1,154 @@
+package stringcoding
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestEliasFano(t *testing.T) {
+	rnd := rand.New(rand.NewSource(71))
+	for _, gap := range []int{1, 3, 100, 5000} {
+		var (
+			values = make([]uint64, 3000)
+			last   uint64
+		)
+		for k := range values {
+			last += uint64(rnd.Intn(gap)) // gaps of 0 are allowed
+			values[k] = last
+		}
+		ef := newEliasFano(uint64(len(values)), last)
+		for _, v := range values {
+			if err := ef.append(v); err != nil {
+				t.Fatalf("eliasFano.append(%d) error = %v", v, err)
+			}
+		}
+		for _, indexed := range []bool{false, true} {
+			if indexed {
+				if err := ef.buildIndex(); err != nil {
+					t.Fatalf("eliasFano.buildIndex() error = %v", err)
+				}
+			}
+			for k, want := range values {
+				if got, err := ef.get(uint64(k)); err != nil || got != want {
+					t.Fatalf("gap %d: eliasFano.get(%d) = %d, %v, want %d", gap, k, got, err, want)
+				}
+			}
+		}
+		if _, err := ef.get(uint64(len(values))); err == nil {
+			t.Errorf("gap %d: eliasFano.get() after the last value should fail", gap)
+		}
+		if err := ef.append(last + 1<<ef.lowWidth); err != nil { // appending after the estimated values
+			t.Errorf("gap %d: eliasFano.append() error = %v", gap, err)
+		}
+		if err := ef.append(0); err != ErrDecreasingValue {
+			t.Errorf("gap %d: eliasFano.append(0) error = %v, want %v", gap, err, ErrDecreasingValue)
+		}
+	}
+}
+
+func TestEliasFanoStarts(t *testing.T) {
+	var (
+		words    = randomWords(3000, "abcdefgh", 72)
+		prefixes = append(randomWords(20, "abcdefgh", 73), "")
+	)
+	eliasFanoImpls := populatedImpls(t, words, WithEliasFanoStarts(true))
+	for name, plain := range populatedImpls(t, words) {
+		eliasFano := eliasFanoImpls[name]
+		for _, prefix := range prefixes {
+			want, _ := plain.FullPrefixSearch(prefix)
+			if got, err := eliasFano.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s.FullPrefixSearch(%q) = %d strings, %v, want %d", name, prefix, len(got), err, len(want))
+			}
+		}
+		for id := uint64(0); id < uint64(len(words)); id += 7 {
+			want, _ := plain.Get(id)
+			if got, err := eliasFano.Get(id); err != nil || got != want {
+				t.Errorf("%s.Get(%d) = %q, %v, want %q", name, id, got, err, want)
+			}
+		}
+		plainSizes, sizes := plain.GetBitDataSize(), eliasFano.GetBitDataSize()
+		if got := eliasFano.StartsEncoding(); got != "EliasFano" {
+			t.Errorf("%s.StartsEncoding() = %q, want %q", name, got, "EliasFano")
+		}
+		if got := plain.StartsEncoding(); got != "Bitvector" {
+			t.Errorf("%s.StartsEncoding() = %q, want %q", name, got, "Bitvector")
+		}
+		if sizes["StartsSize"] >= plainSizes["StartsSize"]/2 {
+			t.Errorf("%s.GetBitDataSize() StartsSize = %d, want less than half of %d", name, sizes["StartsSize"],
+				plainSizes["StartsSize"])
+		}
+		if sizes["StringSize"] != plainSizes["StringSize"] {
+			t.Errorf("%s.GetBitDataSize() StringSize = %d, want %d", name, sizes["StringSize"],
+				plainSizes["StringSize"])
+		}
+	}
+}
+
+func TestEliasFanoStartsUpdates(t *testing.T) {
+	words := randomWords(1000, "abcdefgh", 74)
+	lprc := newLPRC(t, append([]string{}, words...), 1, WithEliasFanoStarts(true), WithDeltaThreshold(0))
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if err := lprc.Insert("zzz"); err != nil {
+		t.Fatalf("LPRC.Insert() error = %v", err)
+	}
+	if got, err := lprc.FullPrefixSearch("zz"); err != nil || !reflect.DeepEqual(got, []string{"zzz"}) {
+		t.Errorf("LPRC.FullPrefixSearch() after Insert = %v, %v", got, err)
+	}
+	if lprc.StartsEncoding() != "EliasFano" {
+		t.Errorf("LPRC.Insert() should keep the Elias-Fano encoding")
+	}
+
+	psrc := newPSRC(t, append([]string{}, words...), 1, WithEliasFanoStarts(true))
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	appended := []string{"zzz", "yy", "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"}
+	for _, s := range appended {
+		id, err := psrc.Append(s)
+		if err != nil {
+			t.Fatalf("PSRC.Append(%q) error = %v", s, err)
+		}
+		if got, err := psrc.Get(id); err != nil || got != s {
+			t.Errorf("PSRC.Get(%d) = %q, %v, want %q", id, got, err, s)
+		}
+	}
+	if got, err := psrc.Get(0); err != nil || got != words[0] {
+		t.Errorf("PSRC.Get(0) after Append = %q, %v, want %q", got, err, words[0])
+	}
+}
+
+func TestEliasFanoStartsPopulateAgain(t *testing.T) {
+	words := randomWords(2000, "abcdefgh", 81)
+	check := func(name string, structure PrefixSearch, want []string) {
+		if err := structure.Populate(); err != nil {
+			t.Fatalf("%s: Populate() again error = %v", name, err)
+		}
+		if got, err := structure.FullPrefixSearch("zz"); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: FullPrefixSearch() = %v, %v, want %v", name, got, err, want)
+		}
+	}
+
+	lprc := newLPRC(t, append([]string{}, words...), 1, WithEliasFanoStarts(true))
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	check("LPRC.Populate()", &lprc, []string{})
+	if err := lprc.PopulateParallel(2); err != nil {
+		t.Errorf("LPRC.PopulateParallel() again error = %v", err)
+	}
+
+	psrc := newPSRC(t, append([]string{}, words...), 1, WithEliasFanoStarts(true))
+	if _, err := psrc.Append("zzz"); err != nil { // it populates the PSRC first
+		t.Fatalf("PSRC.Append() error = %v", err)
+	}
+	check("PSRC.Append()", &psrc, []string{"zzz"})
+
+	inserted := newLPRC(t, append([]string{}, words...), 1, WithEliasFanoStarts(true), WithDeltaThreshold(0))
+	if err := inserted.Insert("zzz"); err != nil { // it codes all the strings at once
+		t.Fatalf("LPRC.Insert() error = %v", err)
+	}
+	check("LPRC.Insert()", &inserted, []string{"zzz"})
+}
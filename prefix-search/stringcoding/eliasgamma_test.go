@@ -1,41 +1,42 @@
 package stringcoding
 
 import (
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+	"reflect"
 	"testing"
 )
 
-func Test_getEliasGammaLength(t *testing.T) {
+func Test_getLengthsCapacity(t *testing.T) {
 	type args struct {
 		strings []string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    uint64
-		wantErr bool
+		name string
+		args args
+		want uint64
 	}{
 		{
 			"eight bit",
 			args{[]string{"a"}},
-			uint64(7), // 2 * log_2(8) + 1 = 2 * 3 + 1 = 6 + 1 = 7
-			false,
+			uint64(9), // 2 * log_2(8 + 16) + 1 = 2 * 4 + 1 = 8 + 1 = 9
+		},
+		{
+			"empty string",
+			args{[]string{"a", ""}},
+			uint64(18), // 9 + 2 * log_2(0 + 16) + 1 = 9 + 2 * 4 + 1 = 18
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getEliasGammaLength(tt.args.strings)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Coding.getEliasGammaLength() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
+			got := getLengthsCapacity(tt.args.strings, EliasGammaCoder{})
 			if got != tt.want {
-				t.Errorf("getEliasGammaLength() = %v, want %v", got, tt.want)
+				t.Errorf("getLengthsCapacity() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCoding_encodeEliasGamma(t *testing.T) {
+func TestCoding_encodeLength(t *testing.T) {
 	type fields struct {
 		// stub values
 		strings []string
@@ -65,15 +66,15 @@ func TestCoding_encodeEliasGamma(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.epsilon)
-			if err := lprc.coding.encodeEliasGamma(tt.args.n); (err != nil) != tt.wantErr {
-				t.Errorf("Coding.encodeEliasGamma() error = %v, wantErr %v", err, tt.wantErr)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.epsilon)
+			if err := lprc.coding.encodeLength(tt.args.n); (err != nil) != tt.wantErr {
+				t.Errorf("Coding.encodeLength() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestCoding_decodeIthEliasGamma(t *testing.T) {
+func TestCoding_decodeIthLength(t *testing.T) {
 	type fields struct {
 		// stub values
 		strings []string
@@ -120,31 +121,31 @@ func TestCoding_decodeIthEliasGamma(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.epsilon)
 			for index, s := range tt.fields.strings {
 				lprc.add(s, uint64(index))
 			}
-			got, err := lprc.coding.decodeIthEliasGamma(tt.args.u)
+			got, err := lprc.coding.decodeIthLength(tt.args.u)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Coding.decodeIthEliasGamma() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("Coding.decodeIthLength() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("Coding.decodeIthEliasGamma() = %v, want %v", got, tt.want)
+				t.Errorf("Coding.decodeIthLength() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCoding_extractNumFromBinary(t *testing.T) {
+func Test_readBinary(t *testing.T) {
 	type fields struct {
 		// stub values
 		strings []string
 		epsilon float64
 	}
 	type args struct {
-		currentIndex uint64
-		zeroCount    uint64
+		position uint64
+		width    uint64
 	}
 	tests := []struct {
 		name    string
@@ -156,37 +157,37 @@ func TestCoding_extractNumFromBinary(t *testing.T) {
 		{
 			"second string val",
 			fields{[]string{"ciao", "cic"}, 20},
-			args{4, 4},
+			args{4, 5},
 			uint64(18),
 			false,
 		},
 		{
 			"error test",
 			fields{[]string{"ciao", "cic"}, 20},
-			args{15, 0},
+			args{15, 1},
 			uint64(0),
 			true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.epsilon)
 			for index, s := range tt.fields.strings {
 				lprc.add(s, uint64(index))
 			}
-			got, err := lprc.coding.extractNumFromBinary(tt.args.currentIndex, tt.args.zeroCount)
+			got, err := readBinary(lprc.coding.Lengths, tt.args.position, tt.args.width)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Coding.extractNumFromBinary() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("readBinary() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("Coding.extractNumFromBinary() = %v, want %v", got, tt.want)
+				t.Errorf("readBinary() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCoding_eliasGammaZeroCount(t *testing.T) {
+func Test_countZeros(t *testing.T) {
 	type fields struct {
 		// stub values
 		strings []string
@@ -219,18 +220,73 @@ func TestCoding_eliasGammaZeroCount(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.epsilon)
 			for index, s := range tt.fields.strings {
 				lprc.add(s, uint64(index))
 			}
-			got, err := lprc.coding.eliasGammaZeroCount(tt.args.idx)
+			got, err := countZeros(lprc.coding.Lengths, tt.args.idx)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Coding.eliasGammaZeroCount() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("countZeros() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("Coding.eliasGammaZeroCount() = %v, want %v", got, tt.want)
+				t.Errorf("countZeros() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestCoding_lengthsReader(t *testing.T) {
+	var (
+		c      = New([]string{"stub"})
+		values = []uint64{}
+	)
+	c.Lengths = bd.New(1<<12, 0)
+	for n := uint64(1); n <= 3*lengthsSampleRate+5; n++ {
+		value := n*n%97 + 1
+		values = append(values, value)
+		if err := c.encodeLength(value); err != nil {
+			t.Fatalf("Coding.encodeLength(%d) error = %v", value, err)
+		}
+	}
+	// the code of the string u is values[u-1], since the first string has no code
+	for u := uint64(1); u <= uint64(len(values)); u++ {
+		got, err := c.decodeIthLength(u)
+		if err != nil || got != values[u-1] {
+			t.Errorf("Coding.decodeIthLength(%d) = %v, %v, want %v", u, got, err, values[u-1])
+		}
+	}
+	if _, err := c.decodeIthLength(uint64(len(values)) + 1); err == nil {
+		t.Errorf("Coding.decodeIthLength() should fail after the last code")
+	}
+
+	reader, err := c.newLengthsReader(lengthsSampleRate - 1)
+	if err != nil {
+		t.Fatalf("Coding.newLengthsReader() error = %v", err)
+	}
+	for u := uint64(lengthsSampleRate - 1); u <= uint64(len(values)); u++ {
+		if got, err := reader.next(); err != nil || got != values[u-1] {
+			t.Errorf("lengthsReader.next() on string %d = %v, %v, want %v", u, got, err, values[u-1])
+		}
+	}
+
+	samples, count := c.lengthsSamples, c.lengthsCount
+	if err := c.indexLengths(); err != nil {
+		t.Fatalf("Coding.indexLengths() error = %v", err)
+	}
+	if !reflect.DeepEqual(samples, c.lengthsSamples) || count != c.lengthsCount {
+		t.Errorf("Coding.indexLengths() = %v, %d, want %v, %d", c.lengthsSamples, c.lengthsCount, samples, count)
+	}
+}
+
+func Test_countZerosLongRun(t *testing.T) {
+	c := New([]string{"stub"})
+	c.Lengths = bd.New(1<<20, 0)
+	for i := 0; i < 1<<19; i++ { // a run of 0s long enough to overflow a recursive count
+		c.Lengths.AppendBit(false)
+	}
+	c.Lengths.AppendBit(true)
+	if got, err := countZeros(c.Lengths, 0); err != nil || got != 1<<19 {
+		t.Errorf("countZeros() = %v, %v, want %v", got, err, 1<<19)
+	}
+}
@@ -1,8 +1,78 @@
 package stringcoding
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
 
 var (
+	// ErrInvalidEpsilon is returned when epsilon is not greater than 0
+	ErrInvalidEpsilon = errors.New("epsilon should be greater than 0")
+	// ErrEmptyString is returned when the empty string is given to a structure that does not keep it
+	ErrEmptyString = errors.New("the empty string is not allowed")
+	// ErrDuplicateString is returned when a string is given twice to a structure that does not keep duplicates
+	ErrDuplicateString = errors.New("duplicated string")
 	// ErrTooShortString is returned when you are trying to access given an index that isn't defined
 	ErrTooShortString = errors.New("the string is too short to contain a prefix of that length")
+	// ErrInvalidFormat is returned by ReadFrom when the data read is not a valid structure
+	ErrInvalidFormat = errors.New("invalid data format")
+	// ErrUnsupportedVersion is returned by ReadFrom when the data has been written by an unknown format version
+	ErrUnsupportedVersion = errors.New("unsupported data format version")
+	// ErrConcurrentModification is returned by an Iterator when the structure has been changed while iterating
+	ErrConcurrentModification = errors.New("the structure has been changed while iterating")
+	// ErrDeletedString is returned when you are trying to access a string that has been deleted
+	ErrDeletedString = errors.New("the string has been deleted")
+	// ErrInvalidIP is returned when an address or a network is neither IPv4 nor IPv6
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrInvalidPrefix is returned when a bit prefix is longer than its bytes or than the keys
+	ErrInvalidPrefix = errors.New("invalid bit prefix")
+	// ErrNotPopulated is returned by WriteTo when the strings of the structure have not been populated yet
+	ErrNotPopulated = errors.New("the structure has not been populated")
+	// ErrUnsupportedCoder is returned by WriteTo when the IntCoder used for Lengths cannot be written
+	ErrUnsupportedCoder = errors.New("unsupported integer code")
+	// ErrDecreasingValue is returned when a value less than the last one is appended to an Elias-Fano sequence
+	ErrDecreasingValue = errors.New("the values of an Elias-Fano sequence should not decrease")
 )
+
+// OptionError is returned when an optional parameter has an invalid value.
+type OptionError struct {
+	Option string
+	Value  interface{}
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid value %v for %s", e.Value, e.Option)
+}
+
+// StringError is returned when the string having index Index cannot be coded or decoded:
+// Err is the error of the failing operation.
+type StringError struct {
+	Index uint64
+	Err   error
+}
+
+func (e *StringError) Error() string {
+	return fmt.Sprintf("string %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns Err.
+func (e *StringError) Unwrap() error {
+	return e.Err
+}
+
+// IDOutOfRangeError is returned by Get when the id does not identify any string.
+type IDOutOfRangeError struct {
+	ID    uint64
+	Count uint64 // number of ids, deleted strings included
+}
+
+func (e *IDOutOfRangeError) Error() string {
+	return fmt.Sprintf("id %d out of range: there are %d ids", e.ID, e.Count)
+}
+
+// Unwrap returns bitdata.ErrIndexOutOfBound, the error of the other accesses out of range.
+func (e *IDOutOfRangeError) Unwrap() error {
+	return bd.ErrIndexOutOfBound
+}
@@ -0,0 +1,74 @@
+package stringcoding
+
+import (
+	"sort"
+	"testing"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+func TestLPRC_Get(t *testing.T) {
+	var (
+		words = randomWords(500, "abcd", 11)
+		lprc  = newLPRC(t, append([]string{}, words...), 1)
+	)
+	sort.Strings(words)
+	if got, err := lprc.Get(2); err != nil || got != words[2] { // not populated yet
+		t.Errorf("LPRC.Get(2) = %v, %v, want %v", got, err, words[2])
+	}
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for id, want := range words {
+		if got, err := lprc.Get(uint64(id)); err != nil || got != want {
+			t.Errorf("LPRC.Get(%d) = %v, %v, want %v", id, got, err, want)
+		}
+	}
+	if err := lprc.DeleteID(7); err != nil {
+		t.Fatalf("LPRC.DeleteID() error = %v", err)
+	}
+	if _, err := lprc.Get(7); err != ErrDeletedString {
+		t.Errorf("LPRC.Get() of a deleted string error = %v, want %v", err, ErrDeletedString)
+	}
+	_, err := lprc.Get(uint64(len(words)))
+	if rangeErr, ok := err.(*IDOutOfRangeError); !ok || rangeErr.ID != uint64(len(words)) ||
+		rangeErr.Count != uint64(len(words)) || rangeErr.Unwrap() != bd.ErrIndexOutOfBound {
+		t.Errorf("LPRC.Get() out of range error = %v, want an *IDOutOfRangeError", err)
+	}
+}
+
+func TestPSRC_Get(t *testing.T) {
+	var (
+		words = append(randomWords(300, "abcd", 12), "cat", "caso", "cat")
+		psrc  = newPSRC(t, append([]string{}, words...), 1, WithMultiset(true))
+	)
+	if got, err := psrc.Get(2); err != nil || got != words[2] { // not populated yet
+		t.Errorf("PSRC.Get(2) = %v, %v, want %v", got, err, words[2])
+	}
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for id, want := range words {
+		if got, err := psrc.Get(uint64(id)); err != nil || got != want {
+			t.Errorf("PSRC.Get(%d) = %v, %v, want %v", id, got, err, want)
+		}
+	}
+	if err := psrc.DeleteID(7); err != nil {
+		t.Fatalf("PSRC.DeleteID() error = %v", err)
+	}
+	if _, err := psrc.Get(7); err != ErrDeletedString {
+		t.Errorf("PSRC.Get() of a deleted string error = %v, want %v", err, ErrDeletedString)
+	}
+	id, err := psrc.Append("dog")
+	if err != nil {
+		t.Fatalf("PSRC.Append() error = %v", err)
+	}
+	if got, err := psrc.Get(id); err != nil || got != "dog" {
+		t.Errorf("PSRC.Get(%d) = %v, %v, want %v", id, got, err, "dog")
+	}
+	if _, err := psrc.Get(uint64(len(words) + 1)); err == nil {
+		t.Errorf("PSRC.Get() should fail for a missing id")
+	} else if _, ok := err.(*IDOutOfRangeError); !ok {
+		t.Errorf("PSRC.Get() out of range error = %v, want an *IDOutOfRangeError", err)
+	}
+}
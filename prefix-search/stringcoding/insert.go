@@ -0,0 +1,166 @@
+package stringcoding
+
+import (
+	"sort"
+	"strings"
+)
+
+// Insert adds the string s to a LPRC, keeping the lexicographic order of the strings.
+// The inserted strings are kept in a sorted buffer which is coded by Flush once it
+// holds more strings than the threshold given by WithDeltaThreshold.
+// If s is already in the LPRC, nothing is done, unless WithMultiset has been given:
+// then a new occurrence of s is added.
+func (lprc *LPRC) Insert(s string) error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	multiset := lprc.options.multiset
+	if len(s) == 0 && !multiset {
+		return ErrEmptyString
+	}
+	i := sort.SearchStrings(lprc.delta, s)
+	if !multiset {
+		if i < len(lprc.delta) && lprc.delta[i] == s {
+			return nil
+		}
+		for _, part := range lprc.parts() {
+			u, coded, err := part.codedIndexOf(s)
+			if err != nil {
+				return err
+			}
+			if coded { // if it has been deleted, it is enough to restore it
+				return part.restore(u)
+			}
+		}
+	}
+	lprc.delta = append(lprc.delta, "")
+	copy(lprc.delta[i+1:], lprc.delta[i:])
+	lprc.delta[i] = s
+	if len(lprc.delta) > lprc.options.deltaThreshold {
+		return lprc.flush()
+	}
+	return nil
+}
+
+// Flush codes the strings inserted with Insert in a new segment of the LPRC, which
+// the queries merge with the strings coded before, so that these are not coded again.
+// The strings coded by Flush get the ids following the ones of the strings coded before.
+// Segments of similar size are coded together, so there are at most a logarithmic
+// number of them, while Compact codes all the strings together.
+func (lprc *LPRC) Flush() error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	return lprc.flush()
+}
+
+// flush is Flush without locking.
+func (lprc *LPRC) flush() error {
+	if len(lprc.delta) == 0 {
+		return nil
+	}
+	if lprc.strings != nil { // nothing has been coded yet, so all the strings are coded together
+		return lprc.rebuild()
+	}
+	segment, err := lprc.newSegment(lprc.delta)
+	if err != nil {
+		return err
+	}
+	lprc.delta = nil
+	lprc.segments = append(lprc.segments, segment)
+	return lprc.mergeSegments()
+}
+
+// rebuild codes again all together the strings that have not been deleted, those
+// coded by the segments and the ones inserted with Insert.
+func (lprc *LPRC) rebuild() error {
+	live := []string{}
+	if lprc.strings != nil { // the LPRC has not been populated yet
+		for u, s := range lprc.strings {
+			isDeleted, err := lprc.deleted.GetBit(uint64(u))
+			if err != nil {
+				return err
+			}
+			if !isDeleted {
+				live = append(live, s)
+			}
+		}
+	} else {
+		for _, part := range lprc.parts() {
+			coded, err := part.codedPrefixSearch("") // deleted strings are skipped
+			if err != nil {
+				return err
+			}
+			live = mergeSorted(live, coded)
+		}
+	}
+	rebuilt, err := NewLPRC(mergeSorted(live, lprc.delta), lprc.Epsilon, withOptions(lprc.options))
+	if err != nil {
+		return err
+	}
+	if err := rebuilt.Populate(); err != nil {
+		return err
+	}
+	lprc.replace(&rebuilt)
+	return nil
+}
+
+// codedIndexOf returns the number of coded strings before s, deleted ones included,
+// that is the index of s if it is among the coded strings, and whether it has been found.
+// Like the other methods on the coded strings, it does not look at the segments.
+func (lprc *LPRC) codedIndexOf(s string) (uint64, bool, error) {
+	if lprc.strings != nil { // the LPRC has not been populated yet
+		i := sort.SearchStrings(lprc.strings, s)
+		return uint64(i), i < len(lprc.strings) && lprc.strings[i] == s, nil
+	}
+	u, err := lprc.lowerBound(s)
+	if err != nil || u == lprc.stringsCount {
+		return u, false, err
+	}
+	cursor, err := lprc.cursorAt(u)
+	if err != nil {
+		return uint64(0), false, err
+	}
+	first, err := cursor.String()
+	return u, first == s, err
+}
+
+// lowerBound returns the index of the first coded string not before s,
+// or the number of coded strings if all of them come before s.
+func (lprc *LPRC) lowerBound(s string) (uint64, error) {
+	if lprc.strings != nil { // the LPRC has not been populated yet
+		return uint64(sort.SearchStrings(lprc.strings, s)), nil
+	}
+	if lprc.stringsCount == 0 {
+		return uint64(0), nil
+	}
+	return lprc.searchPrefix(s, func(cmp int) bool { return cmp >= 0 })
+}
+
+// deltaPrefixSearch returns the strings inserted with Insert, and not coded yet,
+// that start with prefix.
+func (lprc *LPRC) deltaPrefixSearch(prefix string) []string {
+	var (
+		l = sort.SearchStrings(lprc.delta, prefix)
+		r = l
+	)
+	for r < len(lprc.delta) && strings.HasPrefix(lprc.delta[r], prefix) {
+		r++
+	}
+	return lprc.delta[l:r]
+}
+
+// mergeSorted merges two lists of strings in lexicographic order.
+func mergeSorted(a []string, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	merged := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
@@ -0,0 +1,222 @@
+package stringcoding
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLPRC_Insert(t *testing.T) {
+	var (
+		words    = randomWords(1200, "abcd", 5)
+		base     = words[:1000]
+		prefixes = append(randomWords(50, "abcd", 6), "")
+	)
+	for _, threshold := range []int{0, 64, 1000} {
+		lprc := newLPRC(t, append([]string{}, base...), 1, WithDeltaThreshold(threshold))
+		if err := lprc.Populate(); err != nil {
+			t.Fatalf("LPRC.Populate() error = %v", err)
+		}
+		for _, s := range words[1000:] {
+			if err := lprc.Insert(s); err != nil {
+				t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+			}
+		}
+		if len(lprc.delta) > threshold {
+			t.Errorf("threshold %d: LPRC.Insert() kept %d strings uncoded", threshold, len(lprc.delta))
+		}
+		for _, s := range words[:10] { // already in the dictionary
+			if err := lprc.Insert(s); err != nil {
+				t.Errorf("LPRC.Insert(%q) error = %v", s, err)
+			}
+		}
+		for _, prefix := range prefixes {
+			got, err := lprc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Errorf("LPRC.FullPrefixSearch(%q) error = %v", prefix, err)
+				continue
+			}
+			if want := filterPrefix(words, prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("threshold %d: LPRC.FullPrefixSearch(%q) = %v, want %v", threshold, prefix, got, want)
+			}
+		}
+	}
+}
+
+func TestLPRC_InsertBeforePopulate(t *testing.T) {
+	lprc := newLPRC(t, []string{"casotto", "delfino", "zebra"}, 1, WithDeltaThreshold(1))
+	for _, s := range []string{"dente", "cuz", "delfino"} {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	if err := lprc.Insert(""); err != ErrEmptyString {
+		t.Errorf("LPRC.Insert(\"\") error = %v, want %v", err, ErrEmptyString)
+	}
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	want := []string{"casotto", "cuz", "delfino", "dente", "zebra"}
+	if got, err := lprc.FullPrefixSearch(""); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestLPRC_InsertWriteTo(t *testing.T) {
+	var (
+		lprc   = newLPRC(t, []string{"casotto", "delfino", "zebra"}, 1)
+		buffer bytes.Buffer
+		loaded LPRC
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if err := lprc.Insert("dente"); err != nil {
+		t.Fatalf("LPRC.Insert() error = %v", err)
+	}
+	if _, err := lprc.WriteTo(&buffer); err != nil {
+		t.Fatalf("LPRC.WriteTo() error = %v", err)
+	}
+	if !reflect.DeepEqual(lprc.delta, []string{"dente"}) {
+		t.Errorf("LPRC.WriteTo() should not code the inserted strings")
+	}
+	if _, err := loaded.ReadFrom(&buffer); err != nil {
+		t.Fatalf("LPRC.ReadFrom() error = %v", err)
+	}
+	want := []string{"delfino", "dente"}
+	if got, err := loaded.FullPrefixSearch("de"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := loaded.Get(2); err != nil || got != "zebra" {
+		t.Errorf("LPRC.Get(2) = %q, %v, want the id it had before WriteTo", got, err)
+	}
+}
+
+func TestWriteToNotPopulated(t *testing.T) {
+	var (
+		lprc   = newLPRC(t, []string{"casotto", "delfino"}, 1)
+		psrc   = newPSRC(t, []string{"casotto", "delfino"}, 1)
+		buffer bytes.Buffer
+	)
+	if _, err := lprc.WriteTo(&buffer); err != ErrNotPopulated {
+		t.Errorf("LPRC.WriteTo() error = %v, want %v", err, ErrNotPopulated)
+	}
+	if _, err := psrc.WriteTo(&buffer); err != ErrNotPopulated {
+		t.Errorf("PSRC.WriteTo() error = %v, want %v", err, ErrNotPopulated)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("WriteTo() wrote %d bytes of a structure that has not been populated", buffer.Len())
+	}
+}
+
+func TestLPRC_FlushSegments(t *testing.T) {
+	var (
+		words    = randomWords(1500, "abcd", 41)
+		prefixes = append(randomWords(30, "abcd", 42), "")
+		lprc     = newLPRC(t, append([]string{}, words[:1000]...), 1, WithDeltaThreshold(16))
+		live     = map[string]bool{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	codedStrings := lprc.coding.Strings
+	for _, s := range words[1000:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	if lprc.coding.Strings != codedStrings || lprc.stringsCount != 1000 {
+		t.Errorf("LPRC.Insert() coded again the strings coded by Populate")
+	}
+	if len(lprc.segments) == 0 || len(lprc.segments) > 9 {
+		t.Errorf("LPRC.Insert() made %d segments, want between 1 and 9", len(lprc.segments))
+	}
+	for i, s := range words {
+		if i%5 == 0 { // strings of the segments are deleted too
+			if ok, err := lprc.Delete(s); err != nil || !ok {
+				t.Fatalf("LPRC.Delete(%q) = %v, %v, want true", s, ok, err)
+			}
+		} else {
+			live[s] = true
+		}
+	}
+	if err := lprc.Insert(words[1005]); err != nil { // it is restored in its segment
+		t.Fatalf("LPRC.Insert(%q) error = %v", words[1005], err)
+	}
+	live[words[1005]] = true
+
+	check := func(step string, lprc *LPRC) {
+		var liveWords []string
+		for _, s := range words {
+			if live[s] {
+				liveWords = append(liveWords, s)
+			}
+		}
+		sort.Strings(liveWords)
+		if got := lprc.Len(); got != uint64(len(liveWords)) {
+			t.Errorf("%s: LPRC.Len() = %d, want %d", step, got, len(liveWords))
+		}
+		for _, prefix := range prefixes {
+			want := filterPrefix(liveWords, prefix)
+			if got, err := lprc.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) = %v, %v, want %v", step, prefix, got, err, want)
+			}
+			if got, err := lprc.CountPrefix(prefix); err != nil || got != uint64(len(want)) {
+				t.Errorf("%s: LPRC.CountPrefix(%q) = %d, %v, want %d", step, prefix, got, err, len(want))
+			}
+		}
+		for _, s := range words[990:1100] {
+			wantRank := uint64(sort.SearchStrings(liveWords, s))
+			if rank, found := lprc.IndexOf(s); rank != wantRank || found != live[s] {
+				t.Errorf("%s: LPRC.IndexOf(%q) = %d, %v, want %d, %v", step, s, rank, found, wantRank, live[s])
+			}
+			i := sort.SearchStrings(liveWords, s)
+			if pred, ok, err := lprc.Predecessor(s); err != nil || ok != (i > 0) || ok && pred != liveWords[i-1] {
+				t.Errorf("%s: LPRC.Predecessor(%q) = %q, %v, %v", step, s, pred, ok, err)
+			}
+		}
+		got := map[string]bool{}
+		for id := uint64(0); ; id++ {
+			s, err := lprc.Get(id)
+			if _, ok := err.(*IDOutOfRangeError); ok {
+				break
+			}
+			if err == ErrDeletedString {
+				continue
+			}
+			if err != nil || got[s] || !live[s] {
+				t.Fatalf("%s: LPRC.Get(%d) = %q, %v", step, id, s, err)
+			}
+			got[s] = true
+		}
+		if len(got) != len(liveWords)-len(lprc.delta) {
+			t.Errorf("%s: LPRC.Get() found %d coded strings, want %d", step, len(got), len(liveWords)-len(lprc.delta))
+		}
+	}
+	check("inserted", &lprc)
+
+	var (
+		buffer bytes.Buffer
+		loaded LPRC
+	)
+	if _, err := lprc.WriteTo(&buffer); err != nil {
+		t.Fatalf("LPRC.WriteTo() error = %v", err)
+	}
+	if _, err := loaded.ReadFrom(&buffer); err != nil {
+		t.Fatalf("LPRC.ReadFrom() error = %v", err)
+	}
+	if len(loaded.segments) != len(lprc.segments) {
+		t.Errorf("LPRC.ReadFrom() loaded %d segments, want %d", len(loaded.segments), len(lprc.segments))
+	}
+	check("loaded", &loaded)
+
+	lprc.options.compactThreshold = 0
+	if ok, err := lprc.Compact(); err != nil || !ok {
+		t.Fatalf("LPRC.Compact() = %v, %v, want true", ok, err)
+	}
+	if len(lprc.segments) != 0 || len(lprc.delta) != 0 || lprc.stringsCount != uint64(len(live)) {
+		t.Errorf("LPRC.Compact() did not code all the strings together")
+	}
+	check("compacted", &lprc)
+}
@@ -0,0 +1,364 @@
+package stringcoding
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+var (
+	// ErrValueTooLarge is returned when an IntCoder cannot represent the value to encode
+	ErrValueTooLarge = errors.New("the value is too large for the integer code")
+)
+
+// IntCoder is an integer code used to write the values in Lengths.
+// Each code is written from its first bit to its last one, in increasing positions.
+type IntCoder interface {
+	// Name returns the name of the code, together with its parameters.
+	Name() string
+	// Length returns the number of bits of the code of n.
+	Length(n uint64) uint64
+	// Encode appends the code of n to dst.
+	Encode(dst *bd.BitData, n uint64) error
+	// Decode decodes the code starting at position in src,
+	// returning its value and its number of bits.
+	Decode(src *bd.BitData, position uint64) (uint64, uint64, error)
+}
+
+// fitter is implemented by the IntCoders that choose their parameters
+// from the values to encode.
+type fitter interface {
+	// fit returns the IntCoder to use in order to encode values.
+	fit(values []uint64) IntCoder
+}
+
+// EliasDeltaCoder writes a value n > 0 as the Elias gamma code of the number of bits of n
+// followed by the bits of n but the most significant one.
+// For more info check https://en.wikipedia.org/wiki/Elias_delta_coding
+type EliasDeltaCoder struct{}
+
+// Name returns the name of the code.
+func (EliasDeltaCoder) Name() string {
+	return "EliasDelta"
+}
+
+// Length returns the number of bits of the code of n.
+func (EliasDeltaCoder) Length(n uint64) uint64 {
+	bitsCount := uint64(bits.Len64(n))
+	return EliasGammaCoder{}.Length(bitsCount) + bitsCount - 1
+}
+
+// Encode appends the code of n to dst.
+func (EliasDeltaCoder) Encode(dst *bd.BitData, n uint64) error {
+	if n == uint64(0) {
+		return bd.ErrZeroI
+	}
+	bitsCount := uint64(bits.Len64(n))
+	if err := (EliasGammaCoder{}).Encode(dst, bitsCount); err != nil {
+		return err
+	}
+	return appendBinary(dst, n, bitsCount-1) // the most significant bit is always 1
+}
+
+// Decode decodes the code starting at position in src.
+func (EliasDeltaCoder) Decode(src *bd.BitData, position uint64) (uint64, uint64, error) {
+	bitsCount, length, err := EliasGammaCoder{}.Decode(src, position)
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	if bitsCount > 64 {
+		return uint64(0), uint64(0), ErrValueTooLarge
+	}
+	n, err := readBinary(src, position+length, bitsCount-1)
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	return n | uint64(1)<<(bitsCount-1), length + bitsCount - 1, nil
+}
+
+// RiceCoder writes a value n as n / 2^K in unary (that many 0s followed by a 1)
+// followed by the K least significant bits of n.
+// It is the Golomb code having a power of 2 as parameter.
+// For more info check https://en.wikipedia.org/wiki/Golomb_coding
+type RiceCoder struct {
+	K uint
+}
+
+// AutoRiceCoder returns a Rice coder whose parameter K is the one giving the shortest
+// Lengths for the values to encode. It is chosen by Populate: until then Elias gamma is used.
+func AutoRiceCoder() IntCoder {
+	return autoCoder{"AutoRice", fitRice}
+}
+
+// fitRice returns the Rice coder writing values in the least number of bits.
+func fitRice(values []uint64) IntCoder {
+	var (
+		best       = RiceCoder{}
+		bestLength = ^uint64(0)
+	)
+	for k := uint(0); k < 64; k++ {
+		length := uint64(len(values)) * uint64(k+1) // the 1 ending the unary part and the remainder
+		for _, n := range values {
+			length += n >> k
+		}
+		if length < bestLength {
+			best, bestLength = RiceCoder{k}, length
+		}
+	}
+	return best
+}
+
+// Name returns the name of the code, together with K.
+func (coder RiceCoder) Name() string {
+	return fmt.Sprintf("Rice(k=%d)", coder.K)
+}
+
+// Length returns the number of bits of the code of n.
+func (coder RiceCoder) Length(n uint64) uint64 {
+	return n>>coder.K + 1 + uint64(coder.K)
+}
+
+// Encode appends the code of n to dst.
+func (coder RiceCoder) Encode(dst *bd.BitData, n uint64) error {
+	if coder.K >= 64 {
+		return ErrValueTooLarge
+	}
+	if err := appendZeros(dst, n>>coder.K); err != nil { // write the quotient in unary
+		return err
+	}
+	if err := dst.AppendWord(1, 1); err != nil {
+		return err
+	}
+	return appendBinary(dst, n, uint64(coder.K))
+}
+
+// Decode decodes the code starting at position in src.
+func (coder RiceCoder) Decode(src *bd.BitData, position uint64) (uint64, uint64, error) {
+	if coder.K >= 64 {
+		return uint64(0), uint64(0), ErrValueTooLarge
+	}
+	q, err := countZeros(src, position)
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	if q > ^uint64(0)>>coder.K {
+		return uint64(0), uint64(0), ErrValueTooLarge
+	}
+	r, err := readBinary(src, position+q+1, uint64(coder.K))
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	return q<<coder.K | r, q + 1 + uint64(coder.K), nil
+}
+
+// FixedWidthCoder writes each value in binary with Width bits.
+type FixedWidthCoder struct {
+	Width uint
+}
+
+// AutoFixedWidthCoder returns a fixed-width coder whose Width is the number of bits of the
+// greatest value to encode. It is chosen by Populate: until then Elias gamma is used.
+// If a string added later needs a greater value, the Width is chosen again and Lengths is written again.
+func AutoFixedWidthCoder() IntCoder {
+	return autoCoder{"AutoFixedWidth", fitFixedWidth}
+}
+
+// fitFixedWidth returns the fixed-width coder having the least width that can
+// represent all the values.
+func fitFixedWidth(values []uint64) IntCoder {
+	width := 1
+	for _, n := range values {
+		if bits.Len64(n) > width {
+			width = bits.Len64(n)
+		}
+	}
+	return FixedWidthCoder{uint(width)}
+}
+
+// Name returns the name of the code, together with Width.
+func (coder FixedWidthCoder) Name() string {
+	return fmt.Sprintf("FixedWidth(w=%d)", coder.Width)
+}
+
+// Length returns the number of bits of the code of n.
+func (coder FixedWidthCoder) Length(n uint64) uint64 {
+	return uint64(coder.Width)
+}
+
+// Encode appends the code of n to dst.
+func (coder FixedWidthCoder) Encode(dst *bd.BitData, n uint64) error {
+	if coder.Width > 64 || uint(bits.Len64(n)) > coder.Width {
+		return ErrValueTooLarge
+	}
+	return appendBinary(dst, n, uint64(coder.Width))
+}
+
+// Decode decodes the code starting at position in src.
+func (coder FixedWidthCoder) Decode(src *bd.BitData, position uint64) (uint64, uint64, error) {
+	if coder.Width > 64 {
+		return uint64(0), uint64(0), ErrValueTooLarge
+	}
+	n, err := readBinary(src, position, uint64(coder.Width))
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	return n, uint64(coder.Width), nil
+}
+
+// nibblePayload is the number of bits of the value held by each nibble of a NibbleCoder.
+const nibblePayload = 3
+
+// NibbleCoder writes a value as a sequence of nibbles, from the most significant to the
+// least significant one: the first bit of each nibble tells if another nibble follows,
+// the other 3 bits hold a part of the value.
+type NibbleCoder struct{}
+
+// Name returns the name of the code.
+func (NibbleCoder) Name() string {
+	return "Nibble"
+}
+
+// Length returns the number of bits of the code of n.
+func (NibbleCoder) Length(n uint64) uint64 {
+	return (nibblePayload + 1) * nibbleCount(n)
+}
+
+// nibbleCount returns the number of nibbles of the code of n.
+func nibbleCount(n uint64) uint64 {
+	count := (uint64(bits.Len64(n)) + nibblePayload - 1) / nibblePayload
+	if count == 0 { // 0 still needs a nibble
+		count = 1
+	}
+	return count
+}
+
+// Encode appends the code of n to dst.
+func (NibbleCoder) Encode(dst *bd.BitData, n uint64) error {
+	for i := nibbleCount(n); i > 0; i-- {
+		if err := dst.AppendBit(i > 1); err != nil { // is there another nibble?
+			return err
+		}
+		if err := appendBinary(dst, n>>((i-1)*nibblePayload), nibblePayload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode decodes the code starting at position in src.
+func (NibbleCoder) Decode(src *bd.BitData, position uint64) (uint64, uint64, error) {
+	var (
+		n      uint64
+		length uint64
+	)
+	for {
+		if n>>(64-nibblePayload) != 0 {
+			return uint64(0), uint64(0), ErrValueTooLarge
+		}
+		nibble, err := readBinary(src, position+length, nibblePayload+1)
+		if err != nil {
+			return uint64(0), uint64(0), err
+		}
+		n = n<<nibblePayload | nibble&(1<<nibblePayload-1)
+		length += nibblePayload + 1
+		if nibble>>nibblePayload == 0 { // this is the last nibble
+			return n, length, nil
+		}
+	}
+}
+
+// autoCoder is an IntCoder whose parameters are chosen from the values to encode.
+// Until then, it writes Elias gamma codes.
+type autoCoder struct {
+	name   string
+	choose func(values []uint64) IntCoder
+}
+
+func (coder autoCoder) Name() string {
+	return coder.name
+}
+
+func (coder autoCoder) Length(n uint64) uint64 {
+	return EliasGammaCoder{}.Length(n)
+}
+
+func (coder autoCoder) Encode(dst *bd.BitData, n uint64) error {
+	return EliasGammaCoder{}.Encode(dst, n)
+}
+
+func (coder autoCoder) Decode(src *bd.BitData, position uint64) (uint64, uint64, error) {
+	return EliasGammaCoder{}.Decode(src, position)
+}
+
+func (coder autoCoder) fit(values []uint64) IntCoder {
+	return coder.choose(values)
+}
+
+// appendBinary appends the width least significant bits of n to dst,
+// from the most significant to the least significant one.
+func appendBinary(dst *bd.BitData, n uint64, width uint64) error {
+	if width == 0 {
+		return nil
+	}
+	if width > 64 {
+		return bd.ErrIndexOutOfBound
+	}
+	return dst.AppendWord(bits.Reverse64(n)>>(64-width), width) // the most significant bit is appended first
+}
+
+// appendZeros appends n 0s to dst, a word at a time.
+func appendZeros(dst *bd.BitData, n uint64) error {
+	for n > 0 {
+		width := uint64(64)
+		if n < width {
+			width = n
+		}
+		if err := dst.AppendWord(0, width); err != nil {
+			return err
+		}
+		n -= width
+	}
+	return nil
+}
+
+// readBinary reads the width bits starting from position in src as a binary
+// number, from the most significant bit to the least significant one.
+func readBinary(src *bd.BitData, position uint64, width uint64) (uint64, error) {
+	if width == 0 {
+		return uint64(0), nil
+	}
+	if width > 64 {
+		return uint64(0), bd.ErrIndexOutOfBound
+	}
+	word, err := src.GetBits(position, width) // the first bit is the least significant one...
+	if err != nil {
+		return uint64(0), err
+	}
+	return bits.Reverse64(word) >> (64 - width), nil // ...but it has been written as the most significant one
+}
+
+// countZeros counts the number of 0s in src starting from position
+// before the first 1, reading src a word at time.
+func countZeros(src *bd.BitData, position uint64) (uint64, error) {
+	var (
+		zeroCount uint64
+	)
+	for position < src.Len {
+		n := src.Len - position // bits to read in this step
+		if n > 64 {
+			n = 64
+		}
+		word, err := src.GetBits(position, n)
+		if err != nil {
+			return uint64(0), err
+		}
+		if word != 0 { // we found the 1 that ends the 0s
+			return zeroCount + uint64(bits.TrailingZeros64(word)), nil
+		}
+		zeroCount += n
+		position += n
+	}
+	return uint64(0), bd.ErrIndexOutOfBound
+}
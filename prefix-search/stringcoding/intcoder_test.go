@@ -0,0 +1,190 @@
+package stringcoding
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+func TestIntCoder_EncodeDecode(t *testing.T) {
+	coders := []IntCoder{
+		EliasGammaCoder{},
+		EliasDeltaCoder{},
+		RiceCoder{0},
+		RiceCoder{3},
+		FixedWidthCoder{12},
+		NibbleCoder{},
+	}
+	values := []uint64{1, 2, 3, 4, 7, 8, 9, 63, 64, 100, 511, 512, 4095}
+	for _, coder := range coders {
+		t.Run(coder.Name(), func(t *testing.T) {
+			var (
+				dst    = bd.New(1<<14, 0)
+				starts = []uint64{}
+			)
+			for _, n := range values {
+				starts = append(starts, dst.Len)
+				if err := coder.Encode(dst, n); err != nil {
+					t.Fatalf("%s.Encode(%d) error = %v", coder.Name(), n, err)
+				}
+				if got := dst.Len - starts[len(starts)-1]; got != coder.Length(n) {
+					t.Errorf("%s.Encode(%d) wrote %d bits, Length() = %d", coder.Name(), n, got, coder.Length(n))
+				}
+			}
+			for i, n := range values {
+				got, length, err := coder.Decode(dst, starts[i])
+				if err != nil || got != n || length != coder.Length(n) {
+					t.Errorf("%s.Decode(%d) = %v, %v, %v, want %v, %v", coder.Name(), starts[i], got, length, err,
+						n, coder.Length(n))
+				}
+			}
+			if _, _, err := coder.Decode(dst, dst.Len); err == nil {
+				t.Errorf("%s.Decode() should fail after the last code", coder.Name())
+			}
+		})
+	}
+}
+
+func TestIntCoder_EncodeInvalid(t *testing.T) {
+	dst := bd.New(64, 0)
+	if err := (EliasGammaCoder{}).Encode(dst, 0); err == nil {
+		t.Errorf("EliasGammaCoder.Encode(0) should fail")
+	}
+	if err := (EliasDeltaCoder{}).Encode(dst, 0); err == nil {
+		t.Errorf("EliasDeltaCoder.Encode(0) should fail")
+	}
+	if err := (FixedWidthCoder{4}).Encode(dst, 16); err != ErrValueTooLarge {
+		t.Errorf("FixedWidthCoder.Encode() error = %v, want %v", err, ErrValueTooLarge)
+	}
+	if dst.Len != 0 {
+		t.Errorf("a failed Encode() should not write any bit, %d bits written", dst.Len)
+	}
+	for _, coder := range []IntCoder{RiceCoder{0}, FixedWidthCoder{4}, NibbleCoder{}} {
+		if err := coder.Encode(dst, 0); err != nil {
+			t.Errorf("%s.Encode(0) error = %v", coder.Name(), err)
+		}
+	}
+}
+
+func Test_fitCoders(t *testing.T) {
+	values := []uint64{20, 24, 28, 30, 33, 35, 40}
+	if got := fitRice(values); got != (RiceCoder{4}) {
+		t.Errorf("fitRice() = %v, want %v", got, RiceCoder{4})
+	}
+	if got := fitFixedWidth(values); got != (FixedWidthCoder{6}) {
+		t.Errorf("fitFixedWidth() = %v, want %v", got, FixedWidthCoder{6})
+	}
+	if got := fitFixedWidth(nil); got != (FixedWidthCoder{1}) {
+		t.Errorf("fitFixedWidth(nil) = %v, want %v", got, FixedWidthCoder{1})
+	}
+}
+
+func TestWithLengthsCoder(t *testing.T) {
+	var (
+		words    = randomWords(1000, "abcd", 4)
+		prefixes = []string{"", "a", "ab", "dcb", "e"}
+		coders   = []IntCoder{EliasDeltaCoder{}, RiceCoder{2}, AutoRiceCoder(), FixedWidthCoder{10},
+			AutoFixedWidthCoder(), NibbleCoder{}}
+		defaultPSRC = newPSRC(t, append([]string{}, words[:50]...), 1)
+	)
+	if err := defaultPSRC.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for _, coder := range coders {
+		lprc := newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder))
+		if err := lprc.Populate(); err != nil {
+			t.Fatalf("%s: LPRC.Populate() error = %v", coder.Name(), err)
+		}
+		if _, ok := lprc.coding.coder.(fitter); ok {
+			t.Errorf("%s: LPRC.Populate() should fit the coder", coder.Name())
+		}
+		if got := lprc.LengthsCoderName(); got != lprc.coding.coder.Name() {
+			t.Errorf("%s: LPRC.LengthsCoderName() = %q, want %q", coder.Name(), got, lprc.coding.coder.Name())
+		}
+		for _, prefix := range prefixes {
+			got, err := lprc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) error = %v", coder.Name(), prefix, err)
+				continue
+			}
+			if want := filterPrefix(words, prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) = %v, want %v", coder.Name(), prefix, got, want)
+			}
+		}
+
+		var (
+			buffer bytes.Buffer
+			loaded LPRC
+		)
+		if _, err := lprc.WriteTo(&buffer); err != nil {
+			t.Fatalf("%s: LPRC.WriteTo() error = %v", coder.Name(), err)
+		}
+		if _, err := loaded.ReadFrom(&buffer); err != nil {
+			t.Fatalf("%s: LPRC.ReadFrom() error = %v", coder.Name(), err)
+		}
+		if loaded.coding.coder != lprc.coding.coder {
+			t.Errorf("%s: LPRC.ReadFrom() coder = %v, want %v", coder.Name(), loaded.coding.coder,
+				lprc.coding.coder)
+		}
+
+		// PSRC must give the same results it gives with the default coder
+		psrc := newPSRC(t, append([]string{}, words[:50]...), 1, WithLengthsCoder(coder))
+		if err := psrc.Populate(); err != nil {
+			t.Fatalf("%s: PSRC.Populate() error = %v", coder.Name(), err)
+		}
+		for _, prefix := range prefixes[1:] {
+			got, err := psrc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Errorf("%s: PSRC.FullPrefixSearch(%q) error = %v", coder.Name(), prefix, err)
+				continue
+			}
+			if want, _ := defaultPSRC.FullPrefixSearch(prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: PSRC.FullPrefixSearch(%q) = %v, want %v", coder.Name(), prefix, got, want)
+			}
+		}
+	}
+}
+
+func TestAutoCoders_LongerStrings(t *testing.T) {
+	var (
+		words  = randomWords(200, "abcd", 78)
+		longer = []string{strings.Repeat("a", 100), strings.Repeat("a", 99) + "b", "b" + strings.Repeat("a", 99),
+			strings.Repeat("c", 150)}
+	)
+	for _, coder := range []IntCoder{AutoRiceCoder(), AutoFixedWidthCoder()} {
+		psrc := newPSRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder))
+		if err := psrc.Populate(); err != nil {
+			t.Fatalf("%s: PSRC.Populate() error = %v", coder.Name(), err)
+		}
+		lprc := newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder), WithDeltaThreshold(0))
+		if err := lprc.Populate(); err != nil {
+			t.Fatalf("%s: LPRC.Populate() error = %v", coder.Name(), err)
+		}
+		for _, s := range longer { // longer than the strings the coder has been fitted on
+			id, err := psrc.Append(s)
+			if err != nil {
+				t.Fatalf("%s: PSRC.Append(%q) error = %v", coder.Name(), s, err)
+			}
+			if got, err := psrc.Get(id); err != nil || got != s {
+				t.Errorf("%s: PSRC.Get(%d) = %q, %v, want %q", coder.Name(), id, got, err, s)
+			}
+			if err := lprc.Insert(s); err != nil {
+				t.Fatalf("%s: LPRC.Insert(%q) error = %v", coder.Name(), s, err)
+			}
+		}
+		all := append(append([]string{}, words...), longer...)
+		for _, prefix := range []string{"", "a", "b", "cc"} {
+			if got, err := lprc.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, filterPrefix(all, prefix)) {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) = %d strings, %v", coder.Name(), prefix, len(got), err)
+			}
+		}
+		for id, want := range words {
+			if got, err := psrc.Get(uint64(id)); err != nil || got != want {
+				t.Errorf("%s: PSRC.Get(%d) = %q, %v, want %q", coder.Name(), id, got, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package stringcoding
+
+import (
+	"net"
+	"sort"
+)
+
+// IPKeys stores a set of IPv4 or IPv6 addresses in a LPRC as binary keys of
+// 32 or 128 bits, so that they can be searched by prefixes of any number of
+// bits, such as the CIDR blocks.
+// If some address is IPv6 the keys are 128 bits long, and the IPv4 addresses
+// are stored as IPv4-mapped IPv6 addresses.
+type IPKeys struct {
+	lprc LPRC
+	// keyLen is the length of the keys in bytes: net.IPv4len or net.IPv6len.
+	keyLen int
+}
+
+// NewIPKeys returns the IPKeys storing the addresses addrs; duplicated addresses
+// are stored once. It must be populated before being queried.
+// The optional parameters are the ones of NewLPRC.
+func NewIPKeys(addrs []net.IP, epsilon float64, opts ...Option) (IPKeys, error) {
+	keyLen := net.IPv4len
+	for _, addr := range addrs {
+		if addr.To4() == nil {
+			keyLen = net.IPv6len
+		}
+	}
+	keys := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		key, err := ipKey(addr, keyLen)
+		if err != nil {
+			return IPKeys{}, err
+		}
+		keys = append(keys, string(key))
+	}
+	lprc, err := NewLPRC(uniqueStrings(keys), epsilon, opts...)
+	if err != nil {
+		return IPKeys{}, err
+	}
+	return IPKeys{lprc, keyLen}, nil
+}
+
+// Populate populates the LPRC storing the keys.
+func (keys *IPKeys) Populate() error {
+	return keys.lprc.Populate()
+}
+
+// Len returns the number of addresses stored.
+func (keys *IPKeys) Len() uint64 {
+	return keys.lprc.Len()
+}
+
+// PrefixSearchBits returns, in increasing order, the addresses whose first bitLen bits
+// are the first bitLen bits of prefix, e.g. PrefixSearchBits(net.IP{10, 1, 0, 0}, 14)
+// returns the addresses in 10.1.0.0/14.
+// If the keys are IPv6 and prefix is 4 bytes long, it is taken as an IPv4 prefix.
+func (keys *IPKeys) PrefixSearchBits(prefix []byte, bitLen uint) ([]net.IP, error) {
+	if keys.keyLen == net.IPv6len && len(prefix) == net.IPv4len { // the IPv4-mapped prefix
+		prefix, bitLen = net.IP(prefix).To16(), bitLen+8*(net.IPv6len-net.IPv4len)
+	}
+	if bitLen > uint(8*len(prefix)) || len(prefix) > keys.keyLen {
+		return nil, ErrInvalidPrefix
+	}
+	lo := make([]byte, keys.keyLen)
+	copy(lo, prefix)
+	lo = maskBits(lo, bitLen)
+	keys.lprc.guard.rlock()
+	defer keys.lprc.guard.runlock()
+	hi, bounded := nextPrefix(lo, bitLen) // the first key not starting with prefix
+	span := func(part *LPRC) (uint64, uint64, error) {
+		from, err := part.lowerBound(string(lo))
+		if err != nil || !bounded {
+			return from, part.stringsCount, err
+		}
+		to, err := part.lowerBound(string(hi))
+		return from, to, err
+	}
+	var (
+		it    = keys.lprc.newIterator(span, nil)
+		addrs = []net.IP{}
+	)
+	for key, ok := it.Next(); ok; key, ok = it.Next() {
+		addrs = append(addrs, net.IP(key))
+	}
+	return addrs, it.Err()
+}
+
+// CIDRSet stores a set of IPv4 or IPv6 networks in a LPRC, so that the network
+// matching an address with the longest prefix can be found.
+// Each network is stored as a key made of its address followed by a byte
+// containing the length of its prefix. As for IPKeys, if some network is IPv6
+// the IPv4 networks are stored as IPv4-mapped IPv6 networks.
+type CIDRSet struct {
+	lprc LPRC
+	// keyLen is the length of the addresses in bytes: net.IPv4len or net.IPv6len.
+	keyLen int
+	// prefixLens contains the lengths of the prefixes of the networks, in decreasing order.
+	prefixLens []uint
+}
+
+// NewCIDRSet returns the CIDRSet storing the networks. It must be populated
+// before being queried. The optional parameters are the ones of NewLPRC.
+func NewCIDRSet(networks []*net.IPNet, epsilon float64, opts ...Option) (CIDRSet, error) {
+	keyLen := net.IPv4len
+	for _, network := range networks {
+		if _, bits := network.Mask.Size(); bits != 8*net.IPv4len || network.IP.To4() == nil {
+			keyLen = net.IPv6len
+		}
+	}
+	var (
+		keys      = make([]string, 0, len(networks))
+		isPresent = make([]bool, 8*keyLen+1)
+	)
+	for _, network := range networks {
+		ones, bits := network.Mask.Size()
+		if bits == 0 {
+			return CIDRSet{}, ErrInvalidPrefix
+		}
+		addr, err := ipKey(network.IP, keyLen)
+		if err != nil {
+			return CIDRSet{}, err
+		}
+		ones += 8*keyLen - bits // the prefix of an IPv4-mapped network is 96 bits longer
+		keys = append(keys, cidrKey(addr, uint(ones)))
+		isPresent[ones] = true
+	}
+	lprc, err := NewLPRC(uniqueStrings(keys), epsilon, opts...)
+	if err != nil {
+		return CIDRSet{}, err
+	}
+	set := CIDRSet{lprc, keyLen, nil}
+	for ones := len(isPresent) - 1; ones >= 0; ones-- {
+		if isPresent[ones] {
+			set.prefixLens = append(set.prefixLens, uint(ones))
+		}
+	}
+	return set, nil
+}
+
+// Populate populates the LPRC storing the networks.
+func (set *CIDRSet) Populate() error {
+	return set.lprc.Populate()
+}
+
+// LongestPrefixMatch returns the network containing addr that has the longest prefix,
+// and false if no network contains addr.
+// It looks for the network of each prefix length stored, from the longest one.
+func (set *CIDRSet) LongestPrefixMatch(addr net.IP) (*net.IPNet, bool, error) {
+	key, err := ipKey(addr, set.keyLen)
+	if err != nil {
+		return nil, false, err
+	}
+	set.lprc.guard.rlock()
+	defer set.lprc.guard.runlock()
+	for _, ones := range set.prefixLens {
+		network := maskBits(append([]byte{}, key...), ones)
+		_, found, err := set.lprc.indexOf(cidrKey(network, ones))
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			return &net.IPNet{IP: net.IP(network), Mask: net.CIDRMask(int(ones), 8*set.keyLen)}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// ipKey returns the address addr as a key of keyLen bytes.
+func ipKey(addr net.IP, keyLen int) ([]byte, error) {
+	key := addr.To16()
+	if keyLen == net.IPv4len {
+		key = addr.To4()
+	}
+	if key == nil {
+		return nil, ErrInvalidIP
+	}
+	return key, nil
+}
+
+// cidrKey returns the key of the network having address addr and a prefix of ones bits.
+func cidrKey(addr []byte, ones uint) string {
+	return string(maskBits(append([]byte{}, addr...), ones)) + string([]byte{byte(ones)})
+}
+
+// maskBits clears all the bits of key after the first bitLen ones, and returns key.
+func maskBits(key []byte, bitLen uint) []byte {
+	for i := range key {
+		switch {
+		case uint(8*i) >= bitLen:
+			key[i] = 0
+		case uint(8*i+8) > bitLen:
+			key[i] &= ^byte(0xff >> (bitLen % 8))
+		}
+	}
+	return key
+}
+
+// nextPrefix returns the first key, after the keys starting with the first bitLen bits
+// of key, which must be masked by maskBits, and false if there is no such key.
+func nextPrefix(key []byte, bitLen uint) ([]byte, bool) {
+	if bitLen == 0 {
+		return nil, false
+	}
+	next := append([]byte{}, key...)
+	i, carry := (bitLen-1)/8, byte(1)<<(7-(bitLen-1)%8) // the last bit of the prefix
+	for {
+		next[i] += carry
+		if next[i] != 0 { // no carry
+			return next, true
+		}
+		if i == 0 { // all the bits of the prefix were 1
+			return nil, false
+		}
+		i, carry = i-1, 1
+	}
+}
+
+// uniqueStrings sorts the strings and removes the duplicated ones.
+func uniqueStrings(strings []string) []string {
+	sort.Strings(strings)
+	unique := strings[:0]
+	for _, s := range strings {
+		if len(unique) == 0 || s != unique[len(unique)-1] {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
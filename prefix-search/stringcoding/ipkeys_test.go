@@ -0,0 +1,141 @@
+package stringcoding
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIPKeys_PrefixSearchBits(t *testing.T) {
+	var (
+		rnd   = rand.New(rand.NewSource(21))
+		addrs = []net.IP{}
+	)
+	for len(addrs) < 500 {
+		addr := net.IPv4(10, byte(rnd.Intn(8)), byte(rnd.Intn(256)), byte(rnd.Intn(4)))
+		addrs = append(addrs, addr.To4())
+	}
+	addrs = append(addrs, net.IPv4(0, 0, 0, 0).To4(), net.IPv4(255, 255, 255, 255).To4(), addrs[0])
+	keys, err := NewIPKeys(addrs, 1)
+	if err != nil {
+		t.Fatalf("NewIPKeys() error = %v", err)
+	}
+	if err := keys.Populate(); err != nil {
+		t.Fatalf("IPKeys.Populate() error = %v", err)
+	}
+	tests := []struct {
+		prefix net.IP
+		bitLen uint
+	}{
+		{net.IP{10, 1, 0, 0}, 14},
+		{net.IP{10, 4, 0, 0}, 15},
+		{net.IP{10, 2, 17, 0}, 24},
+		{net.IP{10, 3, 200, 2}, 32},
+		{net.IP{10, 0, 0, 0}, 8},
+		{net.IP{255, 0, 0, 0}, 1},
+		{net.IP{0, 0, 0, 0}, 0},
+	}
+	for _, tt := range tests {
+		network := &net.IPNet{IP: tt.prefix, Mask: net.CIDRMask(int(tt.bitLen), 32)}
+		want := []net.IP{}
+		for _, addr := range addrs {
+			if network.Contains(addr) && !containsIP(want, addr) {
+				want = append(want, addr)
+			}
+		}
+		sort.Slice(want, func(i, j int) bool { return bytes.Compare(want[i], want[j]) < 0 })
+		got, err := keys.PrefixSearchBits(tt.prefix, tt.bitLen)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("IPKeys.PrefixSearchBits(%v, %d) = %v, %v, want %v", tt.prefix, tt.bitLen, got, err, want)
+		}
+	}
+	if _, err := keys.PrefixSearchBits(net.IP{10, 1}, 24); err != ErrInvalidPrefix {
+		t.Errorf("IPKeys.PrefixSearchBits() error = %v, want %v", err, ErrInvalidPrefix)
+	}
+}
+
+func TestIPKeys_IPv6(t *testing.T) {
+	addrs := []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), net.ParseIP("2001:db9::1"),
+		net.ParseIP("10.1.2.3")}
+	keys, err := NewIPKeys(addrs, 1)
+	if err != nil {
+		t.Fatalf("NewIPKeys() error = %v", err)
+	}
+	if err := keys.Populate(); err != nil {
+		t.Fatalf("IPKeys.Populate() error = %v", err)
+	}
+	got, err := keys.PrefixSearchBits(net.ParseIP("2001:db8::"), 32)
+	if want := addrs[:2]; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("IPKeys.PrefixSearchBits() = %v, %v, want %v", got, err, want)
+	}
+	got, err = keys.PrefixSearchBits(net.IP{10, 0, 0, 0}, 8) // IPv4 prefix on IPv6 keys
+	if want := addrs[3:]; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("IPKeys.PrefixSearchBits() = %v, %v, want %v", got, err, want)
+	}
+	if _, err := NewIPKeys([]net.IP{{1, 2, 3}}, 1); err != ErrInvalidIP {
+		t.Errorf("NewIPKeys() error = %v, want %v", err, ErrInvalidIP)
+	}
+}
+
+func TestCIDRSet_LongestPrefixMatch(t *testing.T) {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "192.168.0.0/16", "0.0.0.0/0",
+		"2001:db8::/32"} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("net.ParseCIDR(%q) error = %v", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"10.1.2.3", "10.1.2.0/24"},
+		{"10.1.3.3", "10.1.0.0/16"},
+		{"10.200.3.3", "10.0.0.0/8"},
+		{"8.8.8.8", "0.0.0.0/0"},
+		{"2001:db8::1", "2001:db8::/32"},
+		{"2001:db9::1", ""},
+	}
+	set, err := NewCIDRSet(networks, 1)
+	if err != nil {
+		t.Fatalf("NewCIDRSet() error = %v", err)
+	}
+	if err := set.Populate(); err != nil {
+		t.Fatalf("CIDRSet.Populate() error = %v", err)
+	}
+	for _, tt := range tests {
+		got, found, err := set.LongestPrefixMatch(net.ParseIP(tt.addr))
+		if err != nil || found != (tt.want != "") || (found && got.String() != tt.want) {
+			t.Errorf("CIDRSet.LongestPrefixMatch(%s) = %v, %v, %v, want %v", tt.addr, got, found, err, tt.want)
+		}
+	}
+
+	set, err = NewCIDRSet(networks[:4], 1) // IPv4 only
+	if err != nil {
+		t.Fatalf("NewCIDRSet() error = %v", err)
+	}
+	if err := set.Populate(); err != nil {
+		t.Fatalf("CIDRSet.Populate() error = %v", err)
+	}
+	if got, found, err := set.LongestPrefixMatch(net.ParseIP("10.1.2.3")); err != nil || !found ||
+		got.String() != "10.1.2.0/24" {
+		t.Errorf("CIDRSet.LongestPrefixMatch() = %v, %v, %v, want 10.1.2.0/24", got, found, err)
+	}
+	if _, found, err := set.LongestPrefixMatch(net.ParseIP("8.8.8.8")); err != nil || found {
+		t.Errorf("CIDRSet.LongestPrefixMatch() = %v, %v, want false", found, err)
+	}
+}
+
+func containsIP(addrs []net.IP, addr net.IP) bool {
+	for _, a := range addrs {
+		if a.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package stringcoding
+
+import (
+	"context"
+	"strings"
+)
+
+// PrefixIterator returns an Iterator over the strings of the LPRC starting with prefix,
+// in lexicographic order. The strings inserted with Insert are returned too.
+// Each string is decoded from the previous one, so the strings are never
+// decoded more than once. Once the LPRC is changed, for example by Insert, the
+// iterator stops and its Err returns ErrConcurrentModification.
+func (lprc *LPRC) PrefixIterator(prefix string) Iterator {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return newSyncIterator(lprc.prefixIterator(prefix), lprc.guard)
+}
+
+// PrefixIteratorContext is PrefixIterator, but the iterator stops once ctx is done
+// and its Err returns ctx.Err().
+func (lprc *LPRC) PrefixIteratorContext(ctx context.Context, prefix string) Iterator {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	it := lprc.prefixIterator(prefix)
+	it.ctx = ctx
+	return newSyncIterator(it, lprc.guard)
+}
+
+// prefixIterator is PrefixIterator without locking.
+func (lprc *LPRC) prefixIterator(prefix string) *lprcIterator {
+	span := func(part *LPRC) (uint64, uint64, error) {
+		l, r, err := part.prefixRange(prefix)
+		if err != nil || l > r { // no coded string starts with prefix
+			return uint64(0), uint64(0), err
+		}
+		return l, r + 1, nil
+	}
+	return lprc.newIterator(span, lprc.deltaPrefixSearch(prefix))
+}
+
+// newIterator returns an iterator over the strings in delta, which must be sorted,
+// merged with the coded strings of each part of the LPRC in the range [from, to)
+// returned by span for the part.
+func (lprc *LPRC) newIterator(span func(part *LPRC) (uint64, uint64, error), delta []string) *lprcIterator {
+	it := &lprcIterator{delta: delta}
+	for _, part := range lprc.parts() {
+		from, to, err := span(part)
+		if err == nil && from < to {
+			coded := &codedIterator{last: to - 1}
+			coded.cursor, err = part.cursorAt(from)
+			it.coded = append(it.coded, coded)
+		}
+		if err != nil {
+			return &lprcIterator{err: err}
+		}
+	}
+	return it
+}
+
+// FullPrefixSearchN returns at most limit strings starting with prefix, skipping the first
+// offset ones, in the same order as FullPrefixSearch. A negative limit means no limit.
+func (lprc *LPRC) FullPrefixSearchN(prefix string, offset int, limit int) ([]string, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return collect(lprc.prefixIterator(prefix), offset, limit)
+}
+
+// FullPrefixSearchContext is FullPrefixSearch, but it stops once ctx is done, returning ctx.Err().
+func (lprc *LPRC) FullPrefixSearchContext(ctx context.Context, prefix string) ([]string, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	it := lprc.prefixIterator(prefix)
+	it.ctx = ctx
+	return collect(it, 0, -1)
+}
+
+// PrefixIterator returns an Iterator over the strings of the PSRC starting with prefix,
+// in the order they have been added. Each string is decoded from the previous one,
+// so the PSRC is decoded only once. Once the PSRC is changed, for example by Append,
+// the iterator stops and its Err returns ErrConcurrentModification.
+func (psrc *PSRC) PrefixIterator(prefix string) Iterator {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return newSyncIterator(psrc.prefixIterator(prefix), psrc.guard)
+}
+
+// PrefixIteratorContext is PrefixIterator, but the iterator stops once ctx is done
+// and its Err returns ctx.Err().
+func (psrc *PSRC) PrefixIteratorContext(ctx context.Context, prefix string) Iterator {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	it := psrc.prefixIterator(prefix)
+	it.ctx = ctx
+	return newSyncIterator(it, psrc.guard)
+}
+
+// prefixIterator is PrefixIterator without locking.
+func (psrc *PSRC) prefixIterator(prefix string) *psrcIterator {
+	return &psrcIterator{psrc: psrc, prefix: prefix}
+}
+
+// FullPrefixSearchN returns at most limit strings starting with prefix, skipping the first
+// offset ones, in the same order as FullPrefixSearch. A negative limit means no limit.
+func (psrc *PSRC) FullPrefixSearchN(prefix string, offset int, limit int) ([]string, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return collect(psrc.prefixIterator(prefix), offset, limit)
+}
+
+// FullPrefixSearchContext is FullPrefixSearch, but it stops once ctx is done, returning ctx.Err().
+func (psrc *PSRC) FullPrefixSearchContext(ctx context.Context, prefix string) ([]string, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	it := psrc.prefixIterator(prefix)
+	it.ctx = ctx
+	return collect(it, 0, -1)
+}
+
+// lprcIterator merges the coded strings in a range of each part of a LPRC,
+// such as the ones starting with a prefix, with the ones inserted with Insert.
+type lprcIterator struct {
+	// coded contains an iterator over the range of each part having strings in it.
+	coded []*codedIterator
+	// strings inserted with Insert that have not been returned yet.
+	delta []string
+	err   error
+	// ctx stops the iterator once it is done, it is nil if the iterator cannot be canceled.
+	ctx context.Context
+}
+
+// codedIterator returns the coded strings in a range of a part of a LPRC.
+type codedIterator struct {
+	// cursor is on the next coded string to check, it is nil once the range is over.
+	cursor *lprcCursor
+	// index of the last coded string of the range.
+	last uint64
+	// next coded string to return, if hasNext.
+	next    string
+	hasNext bool
+}
+
+// Next returns the next string, or false if there are no more strings.
+func (it *lprcIterator) Next() (string, bool) {
+	if it.err != nil {
+		return "", false
+	}
+	var first *codedIterator // the one having the smallest next coded string
+	for _, coded := range it.coded {
+		if !coded.hasNext {
+			if coded.next, coded.hasNext, it.err = coded.nextCoded(it.ctx); it.err != nil {
+				return "", false
+			}
+		}
+		if coded.hasNext && (first == nil || coded.next < first.next) {
+			first = coded
+		}
+	}
+	if first != nil && (len(it.delta) == 0 || first.next < it.delta[0]) {
+		first.hasNext = false
+		return first.next, true
+	}
+	if len(it.delta) > 0 {
+		s := it.delta[0]
+		it.delta = it.delta[1:]
+		return s, true
+	}
+	return "", false
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *lprcIterator) Err() error {
+	return it.err
+}
+
+// nextCoded returns the next coded string that has not been deleted.
+func (it *codedIterator) nextCoded(ctx context.Context) (string, bool, error) {
+	for it.cursor != nil {
+		if err := canceled(ctx); err != nil {
+			return "", false, err
+		}
+		var (
+			cursor = it.cursor
+			index  = cursor.index
+		)
+		isDeleted, err := cursor.lprc.deleted.GetBit(index)
+		if err != nil {
+			return "", false, err
+		}
+		var s string
+		if !isDeleted {
+			if s, err = cursor.String(); err != nil {
+				return "", false, err
+			}
+		}
+		if index == it.last { // no more strings start with the prefix
+			it.cursor = nil
+		} else if err := cursor.next(); err != nil {
+			return "", false, err
+		}
+		if !isDeleted {
+			return s, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// psrcIterator scans the strings of a PSRC looking for the ones starting with a prefix.
+type psrcIterator struct {
+	psrc   *PSRC
+	prefix string
+	// cursor is on the last string checked, it is nil before the first one.
+	cursor *psrcCursor
+	done   bool
+	err    error
+	// ctx stops the iterator once it is done, it is nil if the iterator cannot be canceled.
+	ctx context.Context
+}
+
+// Next returns the next string, or false if there are no more strings.
+func (it *psrcIterator) Next() (string, bool) {
+	for !it.done {
+		if err := canceled(it.ctx); err != nil {
+			it.err, it.done = err, true
+			break
+		}
+		if err := it.advance(); err != nil {
+			it.err, it.done = err, true
+			break
+		}
+		if it.done {
+			break
+		}
+		isDeleted, err := it.psrc.deleted.GetBit(it.cursor.index)
+		if err != nil {
+			it.err, it.done = err, true
+			break
+		}
+		if isDeleted { // deleted strings are skipped
+			continue
+		}
+		s, err := it.cursor.String()
+		if err != nil {
+			it.err, it.done = err, true
+			break
+		}
+		if strings.HasPrefix(s, it.prefix) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// advance moves the cursor on the next string, setting done if there are no more strings.
+func (it *psrcIterator) advance() error {
+	switch {
+	case it.cursor == nil && it.psrc.stringsCount == 0,
+		it.cursor != nil && it.cursor.index+1 == it.psrc.stringsCount:
+		it.done = true
+		return nil
+	case it.cursor == nil:
+		var err error
+		it.cursor, err = it.psrc.newCursor()
+		return err
+	}
+	return it.cursor.next()
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *psrcIterator) Err() error {
+	return it.err
+}
+
+// collect returns at most limit strings returned by it, skipping the first offset ones.
+// A negative limit means no limit.
+func collect(it Iterator, offset int, limit int) ([]string, error) {
+	found := []string{}
+	for limit < 0 || len(found) < limit {
+		s, ok := it.Next()
+		if !ok {
+			break
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		found = append(found, s)
+	}
+	return found, it.Err()
+}
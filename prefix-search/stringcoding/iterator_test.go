@@ -0,0 +1,130 @@
+package stringcoding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLPRC_PrefixIterator(t *testing.T) {
+	var (
+		words    = randomWords(800, "abcd", 13)
+		prefixes = append(randomWords(30, "abcd", 14), "", "e")
+		lprc     = newLPRC(t, append([]string{}, words[:700]...), 1)
+		live     = []string{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for _, s := range words[700:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for i, s := range words {
+		if i%4 == 0 {
+			if _, err := lprc.Delete(s); err != nil {
+				t.Fatalf("LPRC.Delete(%q) error = %v", s, err)
+			}
+		} else {
+			live = append(live, s)
+		}
+	}
+	for _, prefix := range prefixes {
+		var (
+			want = filterPrefix(live, prefix)
+			it   = lprc.PrefixIterator(prefix)
+			got  = []string{}
+		)
+		for s, ok := it.Next(); ok; s, ok = it.Next() {
+			got = append(got, s)
+		}
+		if err := it.Err(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("LPRC.PrefixIterator(%q) = %v, %v, want %v", prefix, got, err, want)
+		}
+		if _, ok := it.Next(); ok {
+			t.Errorf("LPRC.PrefixIterator(%q).Next() should be false at the end", prefix)
+		}
+		checkFullPrefixSearchN(t, "LPRC", &lprc, prefix, want)
+	}
+}
+
+func TestPSRC_PrefixIterator(t *testing.T) {
+	var (
+		words    = randomWords(300, "abcd", 15)
+		prefixes = append(randomWords(20, "abcd", 16), "", "e")
+		psrc     = newPSRC(t, append([]string{}, words...), 1)
+		live     = []string{}
+	)
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for i, s := range words {
+		if i%4 == 0 {
+			if err := psrc.DeleteID(uint64(i)); err != nil {
+				t.Fatalf("PSRC.DeleteID(%d) error = %v", i, err)
+			}
+		} else {
+			live = append(live, s)
+		}
+	}
+	for _, prefix := range prefixes {
+		want := []string{}
+		for _, s := range live { // PSRC keeps the order the strings have been added
+			if strings.HasPrefix(s, prefix) {
+				want = append(want, s)
+			}
+		}
+		var (
+			it  = psrc.PrefixIterator(prefix)
+			got = []string{}
+		)
+		for s, ok := it.Next(); ok; s, ok = it.Next() {
+			got = append(got, s)
+		}
+		if err := it.Err(); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("PSRC.PrefixIterator(%q) = %v, %v, want %v", prefix, got, err, want)
+		}
+		checkFullPrefixSearchN(t, "PSRC", &psrc, prefix, want)
+	}
+
+	empty := newPSRC(t, []string{}, 1)
+	if s, ok := empty.PrefixIterator("").Next(); ok {
+		t.Errorf("PSRC.PrefixIterator() on an empty PSRC returned %q", s)
+	}
+}
+
+// checkFullPrefixSearchN checks the pages returned by FullPrefixSearchN against all the results.
+func checkFullPrefixSearchN(t *testing.T, name string, impl PrefixSearch, prefix string, all []string) {
+	tests := []struct {
+		offset int
+		limit  int
+	}{
+		{0, -1},
+		{0, 0},
+		{0, 10},
+		{5, 10},
+		{len(all) - 1, 10},
+		{len(all) + 3, 10},
+	}
+	for _, tt := range tests {
+		var (
+			from = tt.offset
+			to   = len(all)
+		)
+		if from > len(all) {
+			from = len(all)
+		}
+		if from < 0 {
+			from = 0
+		}
+		if tt.limit >= 0 && from+tt.limit < to {
+			to = from + tt.limit
+		}
+		got, err := impl.FullPrefixSearchN(prefix, tt.offset, tt.limit)
+		if want := all[from:to]; err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s.FullPrefixSearchN(%q, %d, %d) = %v, %v, want %v", name, prefix, tt.offset, tt.limit,
+				got, err, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package stringcoding
+
+import (
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// lengthsSampleRate tells every how many codes in Lengths we keep the position of the code.
+const lengthsSampleRate = 32
+
+// encodeLength appends the code of the uint64 n to the Lengths bitdata,
+// using the IntCoder of the Coding.
+func (c *Coding) encodeLength(n uint64) error {
+	if c.Lengths == nil {
+		return bd.ErrNotInitBitData
+	}
+	start := c.Lengths.Len
+	if err := c.coder.Encode(c.Lengths, n); err != nil {
+		if err != ErrValueTooLarge || c.fitter == nil {
+			return err
+		}
+		values, err := c.lengthsValues() // n is greater than the values the coder was chosen for
+		if err != nil {
+			return err
+		}
+		return c.refitLengths(append(values, n))
+	}
+	if c.lengthsCount%lengthsSampleRate == 0 { // let's keep track of where this code starts
+		c.lengthsSamples = append(c.lengthsSamples, start)
+	}
+	c.lengthsCount++
+	c.NextLengthsIndex = c.Lengths.Len
+	return nil
+}
+
+// Given an index u, returns the value of the code of the u-th string
+func (c *Coding) decodeIthLength(u uint64) (uint64, error) {
+	if c.Lengths == nil {
+		return uint64(0), bd.ErrNotInitBitData
+	}
+
+	if u == 0 { // the first string does not have a coded length
+		return 0, nil
+	}
+
+	reader, err := c.newLengthsReader(u)
+	if err != nil {
+		return uint64(0), err
+	}
+	return reader.next()
+}
+
+// lengthsReader decodes the codes in Lengths one after the other.
+type lengthsReader struct {
+	c *Coding
+	// position in Lengths of the next code to decode.
+	position uint64
+}
+
+// newLengthsReader returns a lengthsReader whose first code is the one of the u-th string (with u > 0).
+// It starts from the closest sampled code before u, so it never scans Lengths from the beginning.
+func (c *Coding) newLengthsReader(u uint64) (*lengthsReader, error) {
+	if u == 0 || u > c.lengthsCount {
+		return nil, bd.ErrIndexOutOfBound
+	}
+	var (
+		code   = u - 1 // the first string does not have a code
+		reader = &lengthsReader{c, c.lengthsSamples[code/lengthsSampleRate]}
+	)
+	for i := uint64(0); i < code%lengthsSampleRate; i++ { // skip the codes between the sample and u
+		if err := reader.skip(); err != nil {
+			return nil, err
+		}
+	}
+	return reader, nil
+}
+
+// next decodes the next code.
+func (reader *lengthsReader) next() (uint64, error) {
+	n, length, err := reader.c.coder.Decode(reader.c.Lengths, reader.position)
+	if err != nil {
+		return uint64(0), err
+	}
+	reader.position += length
+	return n, nil
+}
+
+// skip moves the reader on the next code without returning it.
+func (reader *lengthsReader) skip() error {
+	_, err := reader.next()
+	return err
+}
+
+// indexLengths rebuilds lengthsSamples and lengthsCount scanning the codes in Lengths.
+// It is used when Lengths has not been written by encodeLength, e.g. when it is loaded.
+func (c *Coding) indexLengths() error {
+	c.lengthsSamples = nil
+	c.lengthsCount = 0
+	reader := &lengthsReader{c, 0}
+	for reader.position < c.Lengths.Len {
+		if c.lengthsCount%lengthsSampleRate == 0 {
+			c.lengthsSamples = append(c.lengthsSamples, reader.position)
+		}
+		if err := reader.skip(); err != nil {
+			return err
+		}
+		c.lengthsCount++
+	}
+	return nil
+}
+
+// fitLengthsCoder lets an IntCoder choosing its parameters from the values to encode
+// pick them from the values written in Lengths, then it writes Lengths again with the
+// chosen IntCoder. Nothing is done for the other IntCoders.
+// The IntCoder is chosen again by encodeLength if a later value cannot be encoded.
+func (c *Coding) fitLengthsCoder() error {
+	f, ok := c.coder.(fitter)
+	if !ok {
+		return nil
+	}
+	values, err := c.lengthsValues()
+	if err != nil {
+		return err
+	}
+	c.fitter = f
+	return c.refitLengths(values)
+}
+
+// lengthsValues decodes all the codes in Lengths.
+func (c *Coding) lengthsValues() ([]uint64, error) {
+	values := make([]uint64, 0, c.lengthsCount)
+	if c.lengthsCount == 0 {
+		return values, nil
+	}
+	reader, err := c.newLengthsReader(1)
+	if err != nil {
+		return nil, err
+	}
+	for u := uint64(1); u <= c.lengthsCount; u++ {
+		n, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// refitLengths writes Lengths again with values, using the IntCoder chosen by the fitter for them.
+func (c *Coding) refitLengths(values []uint64) error {
+	coder := c.fitter.fit(values)
+	capacity := uint64(0)
+	for _, n := range values {
+		capacity += coder.Length(n)
+	}
+	c.coder = coder
+	c.Lengths = bd.New(capacity, 0)
+	c.NextLengthsIndex = 0
+	c.lengthsSamples = nil
+	c.lengthsCount = 0
+	for _, n := range values {
+		if err := c.encodeLength(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package stringcoding
+
+import "sort"
+
+// IndexOf returns the rank of the string s in the lexicographic order of the
+// strings in the LPRC, deleted strings excluded, and whether s is in the LPRC.
+// If s is not in the LPRC, the returned rank is the one s would have once inserted.
+// The lookup runs a binary search on the uncompressed strings, so it does not
+// scan the LPRC. It returns false if the LPRC cannot be read.
+func (lprc *LPRC) IndexOf(s string) (uint64, bool) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	rank, found, err := lprc.indexOf(s)
+	if err != nil {
+		return uint64(0), false
+	}
+	return rank, found
+}
+
+// Contains tells if the string s is in the LPRC.
+func (lprc *LPRC) Contains(s string) bool {
+	_, found := lprc.IndexOf(s)
+	return found
+}
+
+// indexOf returns the rank of s among the strings that have not been deleted,
+// both coded and inserted with Insert, and whether s has been found.
+func (lprc *LPRC) indexOf(s string) (uint64, bool, error) {
+	var (
+		i     = sort.SearchStrings(lprc.delta, s) // inserted strings before s
+		rank  = uint64(i)
+		found = i < len(lprc.delta) && lprc.delta[i] == s
+	)
+	for _, part := range lprc.parts() {
+		u, coded, err := part.liveIndexOf(s)
+		if err != nil {
+			return uint64(0), false, err
+		}
+		deletedBefore, err := part.deletedBefore(u)
+		if err != nil {
+			return uint64(0), false, err
+		}
+		rank += u - deletedBefore
+		found = found || coded
+	}
+	return rank, found, nil
+}
+
+// liveIndexOf returns the number of coded strings before s, deleted ones included,
+// and whether a coded string equal to s has not been deleted.
+func (lprc *LPRC) liveIndexOf(s string) (uint64, bool, error) {
+	l, coded, err := lprc.codedIndexOf(s)
+	if err != nil || !coded {
+		return l, false, err
+	}
+	r := l + 1
+	if lprc.options.multiset { // the strings equal to s are in [l, r)
+		if r, err = lprc.lowerBound(s + "\x00"); err != nil {
+			return uint64(0), false, err
+		}
+	}
+	for u := l; u < r; u++ {
+		isDeleted, err := lprc.deleted.GetBit(u)
+		if err != nil {
+			return uint64(0), false, err
+		}
+		if !isDeleted {
+			return u, true, nil
+		}
+	}
+	return l, false, nil
+}
+
+// deletedBefore returns the number of deleted strings among the first u coded strings.
+func (lprc *LPRC) deletedBefore(u uint64) (uint64, error) {
+	if u == 0 || lprc.deletedCount == 0 {
+		return uint64(0), nil
+	}
+	if err := lprc.buildDeletedIndex(); err != nil {
+		return uint64(0), err
+	}
+	return lprc.deleted.Rank1(u)
+}
+
+// buildDeletedIndex builds the rank/select directory on the deleted strings, which is
+// dropped each time a string is deleted or restored. More goroutines holding the read
+// lock may need it at the same time, so it is built by the first one.
+func (lprc *LPRC) buildDeletedIndex() error {
+	lprc.guard.deletedIndexMu.Lock()
+	defer lprc.guard.deletedIndexMu.Unlock()
+	if lprc.deleted.HasIndex() {
+		return nil
+	}
+	return lprc.deleted.BuildIndex()
+}
@@ -0,0 +1,78 @@
+package stringcoding
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestLPRC_IndexOf(t *testing.T) {
+	var (
+		words   = randomWords(700, "abcd", 9)
+		lookups = append(randomWords(200, "abcde", 10), "", "a", "dddddddddddd")
+		lprc    = newLPRC(t, append([]string{}, words[:600]...), 1)
+		live    = map[string]bool{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for _, s := range words[600:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for i, s := range words {
+		if i%5 == 0 {
+			if _, err := lprc.Delete(s); err != nil {
+				t.Fatalf("LPRC.Delete(%q) error = %v", s, err)
+			}
+		} else {
+			live[s] = true
+		}
+	}
+	sorted := []string{}
+	for s := range live {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+
+	for _, s := range append(lookups, words...) {
+		var (
+			wantRank  = uint64(sort.SearchStrings(sorted, s))
+			wantFound = live[s]
+		)
+		rank, found := lprc.IndexOf(s)
+		if rank != wantRank || found != wantFound {
+			t.Errorf("LPRC.IndexOf(%q) = %d, %v, want %d, %v", s, rank, found, wantRank, wantFound)
+		}
+		if got := lprc.Contains(s); got != wantFound {
+			t.Errorf("LPRC.Contains(%q) = %v, want %v", s, got, wantFound)
+		}
+	}
+}
+
+func TestLPRC_IndexOfBeforePopulate(t *testing.T) {
+	lprc := newLPRC(t, []string{"zebra", "casotto", "delfino"}, 1)
+	tests := []struct {
+		s         string
+		wantRank  uint64
+		wantFound bool
+	}{
+		{"casotto", 0, true},
+		{"delfino", 1, true},
+		{"del", 1, false},
+		{"zebra", 2, true},
+		{"zebre", 3, false},
+	}
+	for _, tt := range tests {
+		if rank, found := lprc.IndexOf(tt.s); rank != tt.wantRank || found != tt.wantFound {
+			t.Errorf("LPRC.IndexOf(%q) = %d, %v, want %d, %v", tt.s, rank, found, tt.wantRank, tt.wantFound)
+		}
+	}
+	empty := newLPRC(t, []string{}, 1)
+	if err := empty.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if rank, found := empty.IndexOf("a"); rank != 0 || found {
+		t.Errorf("LPRC.IndexOf() on an empty LPRC = %d, %v, want 0, false", rank, found)
+	}
+}
@@ -1,9 +1,9 @@
 package stringcoding
 
 import (
+	"context"
 	"fmt"
 	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
-	"github.com/golang-collections/go-datastructures/bitarray"
 	"sort"
 )
 
@@ -22,25 +22,48 @@ type LPRC struct {
 	c                          float64
 	latestCompressedBitWritten uint64
 	strings                    []string
+	stringsCount               uint64
 	isUncompressed             *bd.BitData
+	// deleted has a bit set to 1 for each string deleted with Delete or DeleteID.
+	deleted      *bd.BitData
+	deletedCount uint64
+	options      options
+	// delta contains the strings inserted with Insert that are not coded
+	// yet, in lexicographic order.
+	delta []string
+	// segments contains the strings coded by Flush apart from the other ones,
+	// each of them in a populated LPRC without delta and segments.
+	segments []*LPRC
+	guard    *guard
 }
 
 // NewLPRC returns a LPRC (Locality Preserving Rear Coding): a storage method
 // based on RC (Rear Coding) that stores a string s in an
 // uncompressed way if the latest c|s| bits do not contain
 // an uncompressed string.
-func NewLPRC(strings []string, epsilon float64) LPRC {
-	stringsCount := uint64(len(strings))
-	if epsilon <= float64(0) { // check if epsilon is valid
-		panic("epsilon should be greater than 0")
+// The optional parameters, such as the IntCoder for Lengths, are given by opts.
+// It returns ErrInvalidEpsilon if epsilon is not greater than 0 and an *OptionError
+// if an optional parameter is not valid.
+func NewLPRC(strings []string, epsilon float64, opts ...Option) (LPRC, error) {
+	o := getOptions(opts)
+	if err := o.validate(epsilon); err != nil {
+		return LPRC{}, err
 	}
+	stringsCount := uint64(len(strings))
 	strings = sortLexigographically(strings)
 	c := 2.0 + 2.0/epsilon
-	return LPRC{New(strings),
+	return LPRC{NewWithCoder(strings, o.lengthsCoder),
 		epsilon,
 		c, 0,
 		strings,
-		bd.New(bitarray.NewBitArray(stringsCount), stringsCount)}
+		stringsCount,
+		bd.New(stringsCount, stringsCount),
+		bd.New(stringsCount, stringsCount),
+		0,
+		o,
+		nil,
+		nil,
+		newGuard()}, nil
 }
 
 func sortLexigographically(strings []string) []string {
@@ -50,13 +73,78 @@ func sortLexigographically(strings []string) []string {
 	return strings
 }
 
-// Populate populates all the trie
+// Populate populates all the trie.
+// Once populated, the structure does not keep any reference to the input strings.
+// Without WithMultiset, it returns ErrEmptyString or ErrDuplicateString, before coding
+// any string, if the strings contain the empty string or the same string twice.
+// If a string cannot be coded, it returns a *StringError and the structure is left
+// as it was before, so that it can be populated again.
 func (lprc *LPRC) Populate() error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	return lprc.populate()
+}
+
+// populate is Populate without locking.
+func (lprc *LPRC) populate() error {
+	return lprc.populateContext(context.Background())
+}
+
+// populateContext is PopulateContext without locking.
+func (lprc *LPRC) populateContext(ctx context.Context) error {
+	if !lprc.options.multiset {
+		if err := checkSortedStrings(lprc.strings); err != nil {
+			return err
+		}
+	}
 	for i, s := range lprc.strings {
+		if err := canceled(ctx); err != nil {
+			lprc.unpopulate()
+			return err
+		}
 		if err := lprc.add(s, uint64(i)); err != nil {
+			lprc.unpopulate()
+			return &StringError{uint64(i), err}
+		}
+	}
+	return lprc.endPopulate()
+}
+
+// endPopulate completes the structure once all the strings have been coded.
+func (lprc *LPRC) endPopulate() error {
+	lprc.strings = nil // let the input strings be garbage collected
+	if err := lprc.coding.fitLengthsCoder(); err != nil {
+		return err
+	}
+	if lprc.options.eliasFanoStarts {
+		if err := lprc.coding.encodeStartsWithEliasFano(); err != nil {
 			return err
 		}
 	}
+	lprc.coding.shrink()
+	return lprc.buildIndexes()
+}
+
+// buildIndexes builds the rank/select directories on the BitData
+// that are queried by Retrieval.
+func (lprc *LPRC) buildIndexes() error {
+	if err := lprc.coding.buildStartsIndex(); err != nil {
+		return err
+	}
+	return lprc.isUncompressed.BuildIndex()
+}
+
+// checkSortedStrings returns an error if the strings, in lexicographic
+// order, contain the empty string or the same string twice.
+func checkSortedStrings(strings []string) error {
+	for i, s := range strings {
+		if len(s) == 0 { // it can only be the first one
+			return ErrEmptyString
+		}
+		if i > 0 && strings[i-1] == s {
+			return ErrDuplicateString
+		}
+	}
 	return nil
 }
 
@@ -94,22 +182,21 @@ func (lprc *LPRC) add(s string, index uint64) error {
 	}
 	errAppendBit := coding.Strings.AppendBits(stringToAdd) // 3: append string to Strings bitdata
 	if errAppendBit != nil {
-		panic(errAppendBit) // we don't know if the method has written in the structure
-		// so we have to stop all the process and redo... sorry :(
+		return errAppendBit
 	}
 
 	// 4: append different suffix' length to Lengths
 	prefixLen := bdS.Len - stringToAdd.Len // our string - different suffix
 	if coding.LastString != nil {
-		errAppUL := coding.encodeEliasGamma(calcLen(prefixLen, coding.LastString.Len))
-		if errAppUL != nil { // as above...
-			panic(errAppUL)
+		errAppUL := coding.encodeLength(calcLen(prefixLen, coding.LastString.Len))
+		if errAppUL != nil {
+			return errAppUL
 		}
 	}
 
 	errSetSWO := coding.setStartsWithOffset(stringToAdd) // 5: set the bit of the next string in the Starts array
 	if errSetSWO != nil {
-		panic(errSetSWO)
+		return errSetSWO
 	}
 	coding.LastString = bdS // 6: update last string
 	if !saveUncompressed {  // 7: if the string was saved compressed we have to update latestCompressedBitWritten counter
@@ -120,9 +207,25 @@ func (lprc *LPRC) add(s string, index uint64) error {
 
 // Retrieval (u, l) returns the prefix of the string string(u) with length l.
 // So the returned prefix ends up in the edge (p(u), u).
+// If string(u) has been deleted, it returns ErrDeletedString.
 func (lprc *LPRC) Retrieval(u uint64, l uint64) (string, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	part, u, err := lprc.partOf(u)
+	if err != nil {
+		return "", err
+	}
+	return part.retrieval(u, l)
+}
+
+// retrieval is Retrieval without locking on the u-th string coded by the LPRC,
+// which is not looked for in the segments.
+func (lprc *LPRC) retrieval(u uint64, l uint64) (string, error) {
+	if err := checkDeleted(lprc.deleted, u); err != nil {
+		return "", err
+	}
 	var (
-		stringBuffer = bd.New(bitarray.NewBitArray(l), l) // let's create a buffer in order to store our prefix
+		stringBuffer = bd.New(l, l) // let's create a buffer in order to store our prefix
 	)
 	isUncompressedStringU, errIsCompressed := lprc.isUncompressed.GetBit(u) // check if our string is compressed (we hope no)
 	if errIsCompressed != nil {                                             // isUncompressed has gone wrong
@@ -138,7 +241,7 @@ func (lprc *LPRC) Retrieval(u uint64, l uint64) (string, error) {
 		} // end else
 		err := lprc.populateBuffer(stringBuffer, l, u, uint64(0), l) // we get the first l bits of that string
 		if err != nil {
-			panic(err)
+			return "", &StringError{u, err}
 		}
 	} else { // our string is stored compressed
 		// we'll do Select1(V, Rank1(V, u))
@@ -150,25 +253,32 @@ func (lprc *LPRC) Retrieval(u uint64, l uint64) (string, error) {
 		if err != nil {                                  // i.e. the first uncompressed string before u
 			return "", err
 		}
-		vStarts, err := lprc.coding.Starts.Select1(vPosition + 1) // give me the position where the string v starts in Strings
-		if err != nil {                                           // where v is the first uncompressed string before u
+		vStarts, err := lprc.coding.start(vPosition) // give me the position where the string v starts in Strings
+		if err != nil {                              // where v is the first uncompressed string before u
 			return "", err
 		}
-		vNextStarts, err := lprc.coding.Starts.Select1(vPosition + 1 + 1) // give me the position of the string next to v
-		if err != nil {                                                   // in order to extract the size of string(v)
+		vNextStarts, err := lprc.coding.start(vPosition + 1) // give me the position of the string next to v
+		if err != nil {                                      // in order to extract the size of string(v)
 			return "", err
 		}
 		lengthStringV := vNextStarts - vStarts                                  // that's the length of string(v)
 		err = lprc.populateBuffer(stringBuffer, l, vPosition, 0, lengthStringV) // insert the first l bits of string(v) in the buffer
 		if err != nil {
-			panic(err)
+			return "", &StringError{vPosition, err}
 		}
 
+		lengths, err := lprc.coding.newLengthsReader(vPosition + 1) // we decode the codes of the strings after v one after the other
+		if err != nil {
+			return "", err
+		}
 		for i := vPosition + 1; i <= u; i++ { // for each string i between v and u (we follow the path on the trie in dfs order)
-			li, err := lprc.coding.decodeIthEliasGamma(i) // li is the number of bits to remove in string(p(i)) in order to
-			if err != nil {                               // obtain the prefix for string(i)
+			li, err := lengths.next() // li is the number of bits to remove in string(p(i)) in order to
+			if err != nil {           // obtain the prefix for string(i)
 				return "", err
 			}
+			if li > lengthStringV { // string(p(i)) is shorter than that: Lengths is corrupted
+				return "", bd.ErrIndexOutOfBound
+			}
 			ni := lengthStringV - li                   // this is the length of the common prefix between string(p(i)) and string(i)
 			lengthI, err := lprc.getLengthInStrings(i) // length of the suffix of string(i) in Strings
 			lengthStringV = lengthI + ni               // total length of string(i)
@@ -193,16 +303,56 @@ func (lprc *LPRC) Retrieval(u uint64, l uint64) (string, error) {
 	return stringBuffer.BitToTrimmedString()
 }
 
+// Get returns the string having the given id, that is its index in the lexicographic
+// order of the coded strings, deleted ones included, where the strings coded by each
+// Flush follow the ones coded before. The strings inserted with Insert do not have
+// an id until they are coded.
+// If the id does not identify any string, it returns an *IDOutOfRangeError, while
+// if the string has been deleted, it returns ErrDeletedString.
+func (lprc *LPRC) Get(id uint64) (string, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return lprc.get(id)
+}
+
+// get is Get without locking.
+func (lprc *LPRC) get(id uint64) (string, error) {
+	part, u, err := lprc.partOf(id)
+	if err != nil {
+		return "", err
+	}
+	return part.getCoded(u)
+}
+
+// getCoded returns the string having the given id among the ones coded by
+// the LPRC, which is not looked for in the segments.
+func (lprc *LPRC) getCoded(id uint64) (string, error) {
+	if id >= lprc.stringsCount {
+		return "", &IDOutOfRangeError{id, lprc.stringsCount}
+	}
+	if err := checkDeleted(lprc.deleted, id); err != nil {
+		return "", err
+	}
+	if lprc.strings != nil { // the LPRC has not been populated yet
+		return lprc.strings[id], nil
+	}
+	cursor, err := lprc.cursorAt(id)
+	if err != nil {
+		return "", err
+	}
+	return cursor.String()
+}
+
 func (lprc *LPRC) getLengthInStrings(i uint64) (uint64, error) {
-	startPositionI, err := lprc.coding.Starts.Select1(i + 1)
+	startPositionI, err := lprc.coding.start(i)
 	if err != nil {
 		return uint64(0), err
 	}
 	var startPositionSuccI uint64
-	if (i + 1) == uint64(len(lprc.strings)) {
-		startPositionSuccI = lprc.coding.Starts.Len // u is the last string memorized!
+	if (i + 1) == lprc.stringsCount {
+		startPositionSuccI = lprc.coding.Strings.Len // u is the last string memorized!
 	} else {
-		startPositionSuccI, err = lprc.coding.Starts.Select1(i + 1 + 1) // We need to now where the next string starts
+		startPositionSuccI, err = lprc.coding.start(i + 1) // We need to now where the next string starts
 		if err != nil {
 			return uint64(0), err
 		}
@@ -223,8 +373,8 @@ func (lprc *LPRC) getStringLength(i uint64) (uint64, error) {
 	if err != nil {
 		return uint64(0), err
 	}
-	li, err := lprc.coding.decodeIthEliasGamma(i) // li is the number of bits to remove in string(p(i)) in order to
-	if err != nil {                               // obtain the prefix for string(i)
+	li, err := lprc.coding.decodeIthLength(i) // li is the number of bits to remove in string(p(i)) in order to
+	if err != nil {                           // obtain the prefix for string(i)
 		return uint64(0), err
 	}
 	ni := lengthStringPI - li
@@ -240,11 +390,11 @@ func (lprc *LPRC) populateBuffer(stringBuffer *bd.BitData, l uint64, u uint64, n
 		uPosition uint64
 		maxIt     uint64
 	)
-	if (u + 1) == uint64(len(lprc.strings)) {
+	if (u + 1) == lprc.stringsCount {
 		uPosition = lprc.coding.Strings.Len // u is the last string memorized!
 	} else {
 		var err error
-		uPosition, err = lprc.coding.Starts.Select1(u + 1 + 1) // We need to now where the next string starts
+		uPosition, err = lprc.coding.start(u + 1) // We need to now where the next string starts
 		if err != nil {
 			return err
 		}
@@ -275,62 +425,159 @@ func (lprc *LPRC) populateBuffer(stringBuffer *bd.BitData, l uint64, u uint64, n
 }
 
 // FullPrefixSearch , given a prefix *prefix* returns all the strings that start with that prefix.
+// The strings inserted with Insert are returned too, in lexicographic order with the others.
 func (lprc *LPRC) FullPrefixSearch(prefix string) ([]string, error) {
+	return lprc.FullPrefixSearchN(prefix, 0, -1)
+}
+
+// codedPrefixSearch returns all the coded strings that start with prefix.
+func (lprc *LPRC) codedPrefixSearch(prefix string) ([]string, error) {
 	var (
-		l            uint64                    // first node having *prefix* as prefix
-		r            uint64                    // last node having *prefix* as prefix
-		lenPrefix    = uint64(len(prefix) * 8) // |prefix|
-		totalStrings = uint64(len(lprc.strings))
 		stringBuffer = []string{}
-		found        = false
 	)
-
-	for i := uint64(0); i < totalStrings; i++ {
-		if retrievalI, err := lprc.Retrieval(i, lenPrefix); err != nil {
-			return nil, err // if error was found
-		} else if retrievalI == prefix { // we found the first node having
-			l = i // i is the first string having prefix as prefix
-			found = true
-			break
-		}
+	l, r, err := lprc.prefixRange(prefix)
+	if err != nil {
+		return nil, err
 	}
-	if !found {
-		return []string{}, nil
+	if l > r { // no string starts with prefix
+		return stringBuffer, nil
 	}
 
-	for i := l + 1; i < totalStrings; i++ {
-		if retrievalI, err := lprc.Retrieval(i, lenPrefix); err != nil {
-			return nil, err // if error was found
-		} else if retrievalI != prefix { // we found the first node that does not have prefix as prefix
-			r = i - 1 // r is the last node having prefix as prefix
+	cursor, err := lprc.cursorAt(l)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		isDeleted, err := lprc.deleted.GetBit(cursor.index)
+		if err != nil {
+			return nil, err
+		}
+		if !isDeleted { // deleted strings are skipped
+			s, err := cursor.String()
+			if err != nil {
+				return nil, err
+			}
+			stringBuffer = append(stringBuffer, s)
+		}
+		if cursor.index == r {
 			break
 		}
+		if err := cursor.next(); err != nil {
+			return nil, err
+		}
+	}
+	return stringBuffer, nil
+}
+
+// prefixRange returns the range [l, r] of the strings starting with prefix.
+// If no string starts with prefix, l is greater than r.
+func (lprc *LPRC) prefixRange(prefix string) (uint64, uint64, error) {
+	if lprc.stringsCount == 0 {
+		return uint64(1), uint64(0), nil
+	}
+	l, err := lprc.searchPrefix(prefix, func(cmp int) bool { return cmp >= 0 }) // first string not before prefix
+	if err != nil {
+		return uint64(0), uint64(0), err
+	}
+	r, err := lprc.searchPrefix(prefix, func(cmp int) bool { return cmp > 0 }) // first string after prefix
+	if err != nil {
+		return uint64(0), uint64(0), err
 	}
-	if r < l { // only one string
-		r = l
+	if l == r { // no string starts with prefix
+		return uint64(1), uint64(0), nil
 	}
+	return l, r - 1, nil
+}
 
-	for i := l; i <= r; i++ {
-		stringILen, err := lprc.getStringLength(i)
+// searchPrefix returns the index of the first string for which found returns true,
+// where found is given the result of comparePrefix on the string;
+// since strings are sorted, found must be monotone.
+// It runs a binary search on the uncompressed strings followed by a linear
+// scan of the strings between two consecutive uncompressed ones.
+// If found is false for each string, it returns the number of strings.
+func (lprc *LPRC) searchPrefix(prefix string, found func(int) bool) (uint64, error) {
+	var (
+		stringsCount = lprc.stringsCount
+		searchErr    error
+	)
+	anchorsCount, err := lprc.isUncompressed.Rank1(stringsCount) // number of uncompressed strings
+	if err != nil {
+		return uint64(0), err
+	}
+	isFound := func(cursor *lprcCursor) bool {
+		cmp, err := cursor.comparePrefix(prefix)
 		if err != nil {
-			return nil, err
+			searchErr = err
+			return true
 		}
-		s, err := lprc.Retrieval(i, stringILen)
+		return found(cmp)
+	}
+	// k is the first uncompressed string for which found is true
+	k := uint64(sort.Search(int(anchorsCount), func(k int) bool {
+		if searchErr != nil {
+			return true
+		}
+		anchor, err := lprc.isUncompressed.Select1(uint64(k) + 1)
 		if err != nil {
-			return nil, err
+			searchErr = err
+			return true
 		}
-		stringBuffer = append(stringBuffer, s)
+		cursor, err := lprc.newCursor(anchor)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return isFound(cursor)
+	}))
+	if searchErr != nil {
+		return uint64(0), searchErr
 	}
-	return stringBuffer, nil
+	if k == 0 { // already the first string is fine
+		return uint64(0), nil
+	}
+
+	// the string we are looking for is between the (k-1)-th and the k-th uncompressed strings
+	end := stringsCount
+	if k < anchorsCount {
+		if end, err = lprc.isUncompressed.Select1(k + 1); err != nil {
+			return uint64(0), err
+		}
+	}
+	start, err := lprc.isUncompressed.Select1(k)
+	if err != nil {
+		return uint64(0), err
+	}
+	cursor, err := lprc.newCursor(start)
+	if err != nil {
+		return uint64(0), err
+	}
+	for i := start + 1; i < end; i++ {
+		if err := cursor.next(); err != nil {
+			return uint64(0), err
+		}
+		if isFound(cursor) {
+			return i, searchErr
+		}
+	}
+	return end, searchErr
 }
 
+// getAnchor returns the index of the last uncompressed string not after u.
+func (lprc *LPRC) getAnchor(u uint64) (uint64, error) {
+	return lprc.isUncompressed.PrevOne(u)
+}
+
+// saveUncompressed tells if the string bdS, whose different suffix is stringToAdd, must be saved
+// uncompressed. A duplicate has no different suffix, so it is saved uncompressed to have its own bits.
 func saveUncompressed(stringToAdd *bd.BitData, bdS *bd.BitData, lprc *LPRC) bool {
-	return stringToAdd.Len == bdS.Len || float64(lprc.latestCompressedBitWritten) > lprc.c*float64(bdS.Len)
+	return stringToAdd.Len == bdS.Len || stringToAdd.Len == 0 || float64(lprc.latestCompressedBitWritten) > lprc.c*float64(bdS.Len)
 }
 
 func (lprc *LPRC) String() string {
-	return fmt.Sprintf(`type:%T coding:%v, Epsilon:%v, c:%v, strings:%v, isUncompressed:%v`,
-		lprc, lprc.coding, lprc.Epsilon, lprc.c, lprc.strings, lprc.isUncompressed)
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return fmt.Sprintf(`type:%T coding:%v, Epsilon:%v, c:%v, stringsCount:%v, isUncompressed:%v`,
+		lprc, lprc.coding, lprc.Epsilon, lprc.c, lprc.stringsCount, lprc.isUncompressed)
 }
 
 // it fails if LPRC type does not implements PrefixSearch interface
@@ -340,18 +587,61 @@ func (lprc *LPRC) checkInterface() {
 		return true
 	}
 	var sPs PrefixSearch
-	sLprc := NewLPRC([]string{}, 1.0)
+	sLprc, _ := NewLPRC([]string{}, 1.0)
 	sPs = &sLprc
 	checkFunc(sPs)
 }
 
-// GetBitDataSize returns the size in bits of the BitData used to compress the strings
+// Len returns the number of strings in the LPRC that have not been deleted,
+// including the ones inserted with Insert.
+func (lprc *LPRC) Len() uint64 {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	count, deletedCount := lprc.codedCount()
+	return count - deletedCount + uint64(len(lprc.delta))
+}
+
+// GetBitDataSize returns the size in bits of the memory taken by the BitData used to compress
+// the strings, including their unused capacity, summed over the segments.
 func (lprc *LPRC) GetBitDataSize() map[string]uint64 {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	sizes := lprc.bitDataSize()
+	for _, segment := range lprc.segments {
+		for key, size := range segment.bitDataSize() {
+			sizes[key] += size
+		}
+	}
+	return sizes
+}
+
+// bitDataSize is GetBitDataSize without locking and without the segments.
+func (lprc *LPRC) bitDataSize() map[string]uint64 {
 	sizes := make(map[string]uint64)
-	sizes["StringSize"] = lprc.coding.Strings.Len
-	sizes["StartsSize"] = lprc.coding.Starts.Len
-	sizes["LenghtsSize"] = lprc.coding.Lengths.Len
-	sizes["IsUncompressedSize"] = lprc.isUncompressed.Len
+	sizes["StringSize"] = dataSize(lprc.coding.Strings)
+	startsSize, startsIndexSize := lprc.coding.startsSize()
+	sizes["StartsSize"] = startsSize
+	sizes["LenghtsSize"] = dataSize(lprc.coding.Lengths)
+	sizes["IsUncompressedSize"] = dataSize(lprc.isUncompressed)
+	sizes["RankSelectSize"] = startsIndexSize + lprc.isUncompressed.IndexSize()
+	sizes["DeltaSize"] = bd.GetTotalBitCount(lprc.delta)
+	sizes["TombstonesSize"] = dataSize(lprc.deleted)
 
 	return sizes
 }
+
+// LengthsCoderName returns the name of the IntCoder used for Lengths, which is
+// the one it has chosen once the strings are populated with an automatic IntCoder.
+func (lprc *LPRC) LengthsCoderName() string {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return lprc.coding.coder.Name()
+}
+
+// StartsEncoding returns the name of the representation of the starts of the strings,
+// "EliasFano" once the strings are populated with WithEliasFanoStarts and "Bitvector" otherwise.
+func (lprc *LPRC) StartsEncoding() string {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return lprc.coding.startsEncoding()
+}
@@ -1,9 +1,14 @@
 package stringcoding
 
 import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
-	"reflect"
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
 )
 
 func TestLPRC_Retrieval(t *testing.T) {
@@ -194,7 +199,7 @@ func TestLPRC_Retrieval(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.Epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				lprc.add(s, uint64(i))
 			}
@@ -265,7 +270,7 @@ func TestLPRC_getStringLength(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.Epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				lprc.add(s, uint64(i))
 			}
@@ -383,7 +388,7 @@ func TestLPRC_FullPrefixSearch(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			lprc := NewLPRC(tt.fields.strings, tt.fields.Epsilon)
+			lprc := newLPRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				lprc.add(s, uint64(i))
 			}
@@ -398,3 +403,184 @@ func TestLPRC_FullPrefixSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestLPRC_Populate(t *testing.T) {
+	var (
+		strings = []string{"casotto", "cisonostatierrori", "cuz", "delfino", "delta", "dente", "zebra"}
+		lprc    = newLPRC(t, append([]string{}, strings...), 1)
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if !lprc.coding.Starts.HasIndex() || !lprc.isUncompressed.HasIndex() {
+		t.Errorf("LPRC.Populate() should build the rank/select directories")
+	}
+	if lprc.strings != nil {
+		t.Errorf("LPRC.Populate() should not keep the input strings")
+	}
+	if lprc.GetBitDataSize()["RankSelectSize"] == 0 {
+		t.Errorf("LPRC.GetBitDataSize() should report the size of the rank/select directories")
+	}
+	for i, s := range strings {
+		got, err := lprc.Retrieval(uint64(i), uint64(len(s)*8))
+		if err != nil {
+			t.Errorf("LPRC.Retrieval(%d) error = %v", i, err)
+			continue
+		}
+		if got != s {
+			t.Errorf("LPRC.Retrieval(%d) = %v, want %v", i, got, s)
+		}
+	}
+}
+
+func TestLPRC_CorruptedLengths(t *testing.T) {
+	lprc := newLPRC(t, []string{"casa", "casale", "casaletto", "caso"}, 0.1, WithLengthsCoder(FixedWidthCoder{16}))
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if isUncompressed, err := lprc.isUncompressed.GetBit(1); err != nil || isUncompressed {
+		t.Fatalf("the string 1 should be stored compressed")
+	}
+	for i := uint64(0); i < 16; i++ { // the code of the string 1 is longer than the string 0
+		if err := lprc.coding.Lengths.SetBit(i); err != nil {
+			t.Fatalf("SetBit() error = %v", err)
+		}
+	}
+	if _, err := lprc.Get(1); err != bd.ErrIndexOutOfBound {
+		t.Errorf("LPRC.Get() with corrupted Lengths error = %v, want %v", err, bd.ErrIndexOutOfBound)
+	}
+	if _, err := lprc.Retrieval(1, 8); err != bd.ErrIndexOutOfBound {
+		t.Errorf("LPRC.Retrieval() with corrupted Lengths error = %v, want %v", err, bd.ErrIndexOutOfBound)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		epsilon float64
+		opts    []Option
+		want    error
+	}{
+		{"valid", 0.5, []Option{WithDeltaThreshold(0), WithCompactThreshold(1)}, nil},
+		{"zero epsilon", 0, nil, ErrInvalidEpsilon},
+		{"NaN epsilon", math.NaN(), nil, ErrInvalidEpsilon},
+		{"negative delta threshold", 1, []Option{WithDeltaThreshold(-1)},
+			&OptionError{"WithDeltaThreshold", -1}},
+		{"compact threshold above 1", 1, []Option{WithCompactThreshold(1.5)},
+			&OptionError{"WithCompactThreshold", 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateOptions(tt.epsilon, tt.opts...); !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("ValidateOptions() error = %v, want %v", err, tt.want)
+			}
+			if _, err := NewLPRC([]string{"caso"}, tt.epsilon, tt.opts...); !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("NewLPRC() error = %v, want %v", err, tt.want)
+			}
+			if _, err := NewPSRC([]string{"caso"}, tt.epsilon, tt.opts...); !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("NewPSRC() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// newLPRC returns the LPRC built by NewLPRC, stopping the test if it cannot be built.
+func newLPRC(t testing.TB, strings []string, epsilon float64, opts ...Option) LPRC {
+	lprc, err := NewLPRC(strings, epsilon, opts...)
+	if err != nil {
+		t.Fatalf("NewLPRC() error = %v", err)
+	}
+	return lprc
+}
+
+// newPSRC returns the PSRC built by NewPSRC, stopping the test if it cannot be built.
+func newPSRC(t testing.TB, strings []string, epsilon float64, opts ...Option) PSRC {
+	psrc, err := NewPSRC(strings, epsilon, opts...)
+	if err != nil {
+		t.Fatalf("NewPSRC() error = %v", err)
+	}
+	return psrc
+}
+
+// randomWords returns n distinct words made of the letters in alphabet.
+// populatedImpls returns a populated LPRC and PSRC containing words, built with opts.
+func populatedImpls(t testing.TB, words []string, opts ...Option) map[string]PrefixSearch {
+	var (
+		lprc = newLPRC(t, append([]string{}, words...), 1, opts...)
+		psrc = newPSRC(t, append([]string{}, words...), 1, opts...)
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	return map[string]PrefixSearch{"LPRC": &lprc, "PSRC": &psrc}
+}
+
+func randomWords(n int, alphabet string, seed int64) []string {
+	var (
+		rnd   = rand.New(rand.NewSource(seed))
+		seen  = map[string]bool{}
+		words = []string{}
+	)
+	for len(words) < n {
+		word := make([]byte, 1+rnd.Intn(10))
+		for i := range word {
+			word[i] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		if !seen[string(word)] {
+			seen[string(word)] = true
+			words = append(words, string(word))
+		}
+	}
+	return words
+}
+
+// randomBitData returns a BitData of n random bits and the bits as a slice.
+func randomBitData(rnd *rand.Rand, n uint64) (*bd.BitData, []bool) {
+	var (
+		data = bd.New(n, 0)
+		bits = make([]bool, n)
+	)
+	for i := range bits {
+		bits[i] = rnd.Intn(2) == 1
+		data.AppendBit(bits[i])
+	}
+	return data, bits
+}
+
+// filterPrefix returns the strings in words starting with prefix in lexicographic order.
+func filterPrefix(words []string, prefix string) []string {
+	filtered := []string{}
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			filtered = append(filtered, word)
+		}
+	}
+	sort.Strings(filtered)
+	return filtered
+}
+
+func TestLPRC_FullPrefixSearchRandom(t *testing.T) {
+	var (
+		words    = randomWords(2000, "abcd", 1)
+		prefixes = append(randomWords(100, "abcde", 2), "")
+	)
+	for _, epsilon := range []float64{0.1, 1, 10} {
+		lprc := newLPRC(t, append([]string{}, words...), epsilon)
+		if err := lprc.Populate(); err != nil {
+			t.Fatalf("LPRC.Populate() error = %v", err)
+		}
+		for _, prefix := range prefixes {
+			got, err := lprc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Errorf("LPRC.FullPrefixSearch(%q) error = %v", prefix, err)
+				continue
+			}
+			if want := filterPrefix(words, prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("epsilon %v: LPRC.FullPrefixSearch(%q) = %v, want %v", epsilon, prefix, got, want)
+			}
+		}
+	}
+}
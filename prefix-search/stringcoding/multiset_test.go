@@ -0,0 +1,192 @@
+package stringcoding
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// multisetWords returns words in which some strings are repeated, even one after
+// the other, together with the empty string.
+func multisetWords() []string {
+	words := randomWords(300, "abc", 21)
+	words = append(words, "", "")
+	for i := 0; i < 300; i += 7 {
+		words = append(words, words[i])
+	}
+	return append(words, words[10], words[10], words[10])
+}
+
+func TestLPRC_Multiset(t *testing.T) {
+	var (
+		words    = multisetWords()
+		prefixes = append(randomWords(20, "abc", 22), "")
+		lprc     = newLPRC(t, append([]string{}, words...), 1, WithMultiset(true), WithDeltaThreshold(4))
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	check := func(step string, words []string) {
+		for _, prefix := range prefixes {
+			want := filterPrefix(words, prefix)
+			if got, err := lprc.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: LPRC.FullPrefixSearch(%q) = %v, %v, want %v", step, prefix, got, err, want)
+			}
+			if got, err := lprc.CountPrefix(prefix); err != nil || got != uint64(len(want)) {
+				t.Errorf("%s: LPRC.CountPrefix(%q) = %d, %v, want %d", step, prefix, got, err, len(want))
+			}
+		}
+		if got := lprc.Len(); got != uint64(len(words)) {
+			t.Errorf("%s: LPRC.Len() = %d, want %d", step, got, len(words))
+		}
+	}
+	check("populated", words)
+
+	sorted := filterPrefix(words, "")
+	for id, want := range sorted {
+		if got, err := lprc.Get(uint64(id)); err != nil || got != want {
+			t.Errorf("LPRC.Get(%d) = %q, %v, want %q", id, got, err, want)
+		}
+	}
+	if rank, found := lprc.IndexOf(""); !found || rank != 0 {
+		t.Errorf("LPRC.IndexOf(\"\") = %d, %v, want 0, true", rank, found)
+	}
+
+	for _, s := range []string{"", words[10], "ccccccccccc"} { // each Insert adds an occurrence
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+		words = append(words, s)
+	}
+	check("inserted", words)
+
+	for i := 0; i < 4; i++ { // a single occurrence is removed each time
+		if ok, err := lprc.Delete(words[10]); err != nil || !ok {
+			t.Fatalf("LPRC.Delete(%q) = %v, %v, want true", words[10], ok, err)
+		}
+	}
+	if ok, err := lprc.Delete(""); err != nil || !ok {
+		t.Fatalf("LPRC.Delete(\"\") = %v, %v, want true", ok, err)
+	}
+	var (
+		left    = []string{}
+		removed = map[string]int{words[10]: 4, "": 1}
+	)
+	for _, s := range words {
+		if removed[s] > 0 {
+			removed[s]--
+		} else {
+			left = append(left, s)
+		}
+	}
+	check("deleted", left)
+	if !lprc.Contains(words[10]) {
+		t.Errorf("LPRC.Contains(%q) = false, want true", words[10])
+	}
+
+	var (
+		buffer bytes.Buffer
+		loaded LPRC
+	)
+	if _, err := lprc.WriteTo(&buffer); err != nil {
+		t.Fatalf("LPRC.WriteTo() error = %v", err)
+	}
+	if _, err := loaded.ReadFrom(&buffer); err != nil {
+		t.Fatalf("LPRC.ReadFrom() error = %v", err)
+	}
+	if err := loaded.Insert(""); err != nil { // the loaded LPRC keeps duplicates too
+		t.Fatalf("LPRC.Insert(\"\") after ReadFrom error = %v", err)
+	}
+	want := []string{"", "", ""} // two of the three empty strings are left, then one is inserted
+	if got, err := loaded.FullPrefixSearchN("", 0, 3); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.FullPrefixSearchN() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestPSRC_Multiset(t *testing.T) {
+	var (
+		words = multisetWords()
+		psrc  = newPSRC(t, append([]string{}, words...), 1, WithMultiset(true))
+	)
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	for _, s := range []string{"", "", words[0], words[0]} {
+		if _, err := psrc.Append(s); err != nil {
+			t.Fatalf("PSRC.Append(%q) error = %v", s, err)
+		}
+		words = append(words, s)
+	}
+	for id, want := range words {
+		if got, err := psrc.Get(uint64(id)); err != nil || got != want {
+			t.Errorf("PSRC.Get(%d) = %q, %v, want %q", id, got, err, want)
+		}
+	}
+	for _, prefix := range []string{"", "a", "ab", words[10]} {
+		want := filterPrefix(words, prefix)
+		if got, err := psrc.CountPrefix(prefix); err != nil || got != uint64(len(want)) {
+			t.Errorf("PSRC.CountPrefix(%q) = %d, %v, want %d", prefix, got, err, len(want))
+		}
+	}
+	if ok, err := psrc.Delete(""); err != nil || !ok {
+		t.Fatalf("PSRC.Delete(\"\") = %v, %v, want true", ok, err)
+	}
+	if got, want := psrc.Len(), uint64(len(words)-4); got != want {
+		t.Errorf("PSRC.Len() = %d, want %d", got, want)
+	}
+}
+
+func TestRejectDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		strings []string
+		want    error
+	}{
+		{"distinct", []string{"delfino", "caso", "cat"}, nil},
+		{"empty string", []string{"delfino", "", "cat"}, ErrEmptyString},
+		{"duplicate", []string{"cat", "delfino", "caso", "cat"}, ErrDuplicateString},
+		{"adjacent duplicate", []string{"caso", "cat", "cat"}, ErrDuplicateString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lprc := newLPRC(t, append([]string{}, tt.strings...), 1)
+			if err := lprc.Populate(); err != tt.want {
+				t.Errorf("LPRC.Populate() error = %v, want %v", err, tt.want)
+			}
+			psrc := newPSRC(t, append([]string{}, tt.strings...), 1)
+			if err := psrc.Populate(); err != tt.want {
+				t.Errorf("PSRC.Populate() error = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && (lprc.coding.Strings.Len != 0 || psrc.coding.Strings.Len != 0) {
+				t.Errorf("Populate() should not code any string when it fails")
+			}
+		})
+	}
+}
+
+func TestPSRC_AppendDuplicate(t *testing.T) {
+	psrc := newPSRC(t, []string{"caso", "cat"}, 1)
+	if _, err := psrc.Append("cat"); err != ErrDuplicateString {
+		t.Errorf("PSRC.Append(\"cat\") error = %v, want %v", err, ErrDuplicateString)
+	}
+	if _, err := psrc.Append("ca"); err != nil {
+		t.Errorf("PSRC.Append(\"ca\") error = %v", err)
+	}
+	if _, err := psrc.Delete("cat"); err != nil {
+		t.Fatalf("PSRC.Delete() error = %v", err)
+	}
+	if id, err := psrc.Append("cat"); err != nil || id != 3 { // a deleted string can be appended again
+		t.Errorf("PSRC.Append(\"cat\") after Delete = %d, %v, want 3", id, err)
+	}
+	if got := psrc.Len(); got != 3 {
+		t.Errorf("PSRC.Len() = %d, want 3", got)
+	}
+	for _, s := range []string{"caso", "ca", "cat"} { // appended strings are found too
+		if _, err := psrc.Append(s); err != ErrDuplicateString {
+			t.Errorf("PSRC.Append(%q) error = %v, want %v", s, err, ErrDuplicateString)
+		}
+	}
+	if got := len(psrc.hashes); got != 3 {
+		t.Errorf("PSRC.Append() kept %d hashes, want 3", got)
+	}
+}
@@ -0,0 +1,111 @@
+package stringcoding
+
+// Option sets an optional parameter of a LPRC or a PSRC.
+type Option func(*options)
+
+// defaultDeltaThreshold is the default number of strings inserted in a LPRC
+// that are kept uncoded before being merged in the structure.
+const defaultDeltaThreshold = 1024
+
+// defaultCompactThreshold is the default ratio of deleted strings
+// above which Compact codes the structure again.
+const defaultCompactThreshold = 0.25
+
+// options contains the optional parameters of a LPRC or a PSRC.
+type options struct {
+	lengthsCoder     IntCoder
+	deltaThreshold   int
+	compactThreshold float64
+	multiset         bool
+	eliasFanoStarts  bool
+}
+
+// getOptions returns the options obtained applying opts to the default ones.
+func getOptions(opts []Option) options {
+	o := options{
+		lengthsCoder:     EliasGammaCoder{},
+		deltaThreshold:   defaultDeltaThreshold,
+		compactThreshold: defaultCompactThreshold,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validate returns ErrInvalidEpsilon if epsilon is not greater than 0, or an
+// *OptionError if an optional parameter has an invalid value.
+func (o options) validate(epsilon float64) error {
+	if !(epsilon > 0) { // NaN is not valid too
+		return ErrInvalidEpsilon
+	}
+	if o.deltaThreshold < 0 {
+		return &OptionError{"WithDeltaThreshold", o.deltaThreshold}
+	}
+	if !(o.compactThreshold >= 0 && o.compactThreshold <= 1) {
+		return &OptionError{"WithCompactThreshold", o.compactThreshold}
+	}
+	return nil
+}
+
+// ValidateOptions checks the parameters of NewLPRC and NewPSRC without building anything:
+// it returns the error they would return for epsilon and opts.
+func ValidateOptions(epsilon float64, opts ...Option) error {
+	return getOptions(opts).validate(epsilon)
+}
+
+// withOptions sets all the optional parameters as in o.
+func withOptions(o options) Option {
+	return func(dst *options) {
+		*dst = o
+	}
+}
+
+// WithLengthsCoder sets the IntCoder used to write the values in Lengths.
+// By default Elias gamma is used.
+func WithLengthsCoder(coder IntCoder) Option {
+	return func(o *options) {
+		if coder != nil {
+			o.lengthsCoder = coder
+		}
+	}
+}
+
+// WithDeltaThreshold sets how many strings inserted in a LPRC with Insert are kept
+// in a sorted buffer before coding them together with the other strings.
+// With 0, each string is coded as soon as it is inserted. It must not be negative.
+func WithDeltaThreshold(threshold int) Option {
+	return func(o *options) {
+		o.deltaThreshold = threshold
+	}
+}
+
+// WithCompactThreshold sets the ratio of deleted strings, between 0 and 1,
+// above which Compact codes again the strings that have not been deleted.
+func WithCompactThreshold(ratio float64) Option {
+	return func(o *options) {
+		o.compactThreshold = ratio
+	}
+}
+
+// WithMultiset sets whether the empty string and the duplicated strings are kept.
+// With true, each occurrence is a string of its own: it has its own id and it is
+// returned by the queries as many times as it occurs.
+// By default they are rejected: Populate and PSRC.Append return ErrEmptyString or
+// ErrDuplicateString, and Insert does nothing for a string that is already in a LPRC.
+func WithMultiset(multiset bool) Option {
+	return func(o *options) {
+		o.multiset = multiset
+	}
+}
+
+// WithEliasFanoStarts sets whether the positions in Strings where the strings start are
+// represented with Elias-Fano instead of Starts, a bitvector as long as Strings with a 1
+// for each string. With n strings in u bits, Elias-Fano takes about 2 + log_2(u/n) bits
+// for each string instead of u/n, and it still finds the start of a string in constant time.
+// By default Starts is used.
+func WithEliasFanoStarts(eliasFano bool) Option {
+	return func(o *options) {
+		o.eliasFanoStarts = eliasFano
+	}
+}
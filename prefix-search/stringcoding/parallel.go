@@ -0,0 +1,147 @@
+package stringcoding
+
+import (
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// minBlockSize is the minimum number of strings coded by a goroutine in PopulateParallel,
+// so that small dictionaries are not split in blocks that are not worth a goroutine.
+const minBlockSize = 1024
+
+// PopulateParallel populates the trie like Populate, coding the strings on workers goroutines.
+// The sorted strings are split in blocks of consecutive strings, each one starting with an
+// uncompressed string: since an uncompressed string does not depend on the strings before it,
+// the blocks are coded independently and then concatenated.
+// The structure differs from the one built by Populate only because the first string of each
+// block is stored uncompressed, so it answers the same to each query. With a single block
+// it is the same as the one built by Populate.
+// If workers is not greater than 0, a goroutine for each CPU is used.
+func (lprc *LPRC) PopulateParallel(workers int) error {
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	bounds := splitBlocks(uint64(len(lprc.strings)), workers)
+	if len(bounds) <= 2 { // a single block
+		return lprc.populate()
+	}
+	if !lprc.options.multiset {
+		if err := checkSortedStrings(lprc.strings); err != nil {
+			return err
+		}
+	}
+	var (
+		coded = make([]*LPRC, len(bounds)-1)
+		errs  = make([]error, len(bounds)-1)
+		wg    sync.WaitGroup
+	)
+	for b := range coded {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			coded[b], errs[b] = lprc.codeBlock(bounds[b], bounds[b+1])
+		}(b)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for b, block := range coded {
+		if err := lprc.appendBlock(block, bounds[b]); err != nil {
+			return err
+		}
+	}
+	return lprc.endPopulate()
+}
+
+// splitBlocks splits n strings in at most workers blocks of at least minBlockSize strings.
+// It returns the index of the first string of each block, followed by n.
+// Since the first string has no code in Lengths, the blocks after the first one start
+// from 1 plus a multiple of lengthsSampleRate: this way the first code of each block
+// is sampled, and the samples of the blocks are samples of the whole Lengths.
+func splitBlocks(n uint64, workers int) []uint64 {
+	codes := n // the number of codes in Lengths
+	if codes > 0 {
+		codes--
+	}
+	size := (codes + uint64(workers) - 1) / uint64(workers)
+	if size < minBlockSize {
+		size = minBlockSize
+	}
+	size = (size + lengthsSampleRate - 1) / lengthsSampleRate * lengthsSampleRate
+	bounds := []uint64{0}
+	for start := 1 + size; start < n; start += size {
+		bounds = append(bounds, start)
+	}
+	return append(bounds, n)
+}
+
+// codeBlock codes the strings with index in [start, end) in a new LPRC, whose first
+// string is stored uncompressed. The LPRC is not complete: it is only meant to be
+// appended to lprc with appendBlock.
+func (lprc *LPRC) codeBlock(start, end uint64) (*LPRC, error) {
+	var (
+		strings = lprc.strings[start:end]
+		count   = uint64(len(strings))
+		block   = &LPRC{
+			coding:         NewWithCoder(strings, lprc.coding.coder),
+			c:              lprc.c,
+			isUncompressed: bd.New(count, count),
+		}
+	)
+	if start > 0 { // the block follows a string, the one whose length is coded for its first string
+		last, err := bd.GetBitData(lprc.strings[start-1] + "\x00")
+		if err != nil {
+			return nil, &StringError{start - 1, err}
+		}
+		block.coding.LastString = last
+		block.latestCompressedBitWritten = math.MaxUint64 // the first string must be stored uncompressed
+	}
+	for i, s := range strings {
+		if err := block.add(s, uint64(i)); err != nil {
+			return nil, &StringError{start + uint64(i), err}
+		}
+	}
+	return block, nil
+}
+
+// appendBlock appends to lprc the strings coded by codeBlock in block, the first of which
+// has index start, given by splitBlocks.
+func (lprc *LPRC) appendBlock(block *LPRC, start uint64) error {
+	coding := lprc.coding
+	for _, sample := range block.coding.lengthsSamples {
+		coding.lengthsSamples = append(coding.lengthsSamples, coding.Lengths.Len+sample)
+	}
+	coding.lengthsCount += block.coding.lengthsCount
+	for _, bits := range []struct{ dst, src *bd.BitData }{
+		{coding.Strings, block.coding.Strings},
+		{coding.Starts, block.coding.Starts},
+		{coding.Lengths, block.coding.Lengths},
+	} {
+		bits.dst.Reserve(bits.src.Len)
+		if err := bits.dst.AppendBits(bits.src); err != nil {
+			return err
+		}
+	}
+	for i := uint64(0); i < block.isUncompressed.Len; i++ {
+		isUncompressed, err := block.isUncompressed.GetBit(i)
+		if err != nil {
+			return err
+		}
+		if isUncompressed {
+			if err := lprc.isUncompressed.SetBit(start + i); err != nil {
+				return err
+			}
+		}
+	}
+	coding.NextLengthsIndex = coding.Lengths.Len
+	coding.LastString = block.coding.LastString
+	lprc.latestCompressedBitWritten = block.latestCompressedBitWritten
+	return nil
+}
@@ -0,0 +1,84 @@
+package stringcoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLPRC_PopulateParallel(t *testing.T) {
+	var (
+		words    = randomWords(5*minBlockSize, "abcdef", 41)
+		prefixes = append(randomWords(30, "abcdef", 42), "")
+		coders   = []IntCoder{EliasGammaCoder{}, AutoRiceCoder()}
+	)
+	for _, coder := range coders {
+		sequential := newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder))
+		if err := sequential.Populate(); err != nil {
+			t.Fatalf("LPRC.Populate() error = %v", err)
+		}
+		for _, workers := range []int{1, 2, 3, 8, 0} {
+			lprc := newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder))
+			if err := lprc.PopulateParallel(workers); err != nil {
+				t.Fatalf("LPRC.PopulateParallel(%d) error = %v", workers, err)
+			}
+			if workers == 1 && !reflect.DeepEqual(lprc.GetBitDataSize(), sequential.GetBitDataSize()) {
+				t.Errorf("LPRC.PopulateParallel(1) sizes = %v, want %v", lprc.GetBitDataSize(),
+					sequential.GetBitDataSize())
+			}
+			for id := uint64(0); id < sequential.Len(); id++ {
+				want, _ := sequential.Get(id)
+				if got, err := lprc.Get(id); err != nil || got != want {
+					t.Fatalf("PopulateParallel(%d): LPRC.Get(%d) = %q, %v, want %q", workers, id, got, err, want)
+				}
+			}
+			for _, prefix := range prefixes {
+				want := filterPrefix(words, prefix)
+				if got, err := lprc.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("PopulateParallel(%d): LPRC.FullPrefixSearch(%q) = %d strings, %v, want %d",
+						workers, prefix, len(got), err, len(want))
+				}
+			}
+			if err := lprc.Insert("zz"); err != nil {
+				t.Errorf("PopulateParallel(%d): LPRC.Insert() error = %v", workers, err)
+			}
+		}
+	}
+}
+
+func TestLPRC_PopulateParallelErrors(t *testing.T) {
+	words := randomWords(3*minBlockSize, "abcd", 43)
+	lprc := newLPRC(t, append(append([]string{}, words...), words[minBlockSize]), 1)
+	if err := lprc.PopulateParallel(3); err != ErrDuplicateString {
+		t.Errorf("LPRC.PopulateParallel() error = %v, want %v", err, ErrDuplicateString)
+	}
+
+	multiset := newLPRC(t, append(append([]string{}, words...), words[:minBlockSize]...), 1, WithMultiset(true))
+	if err := multiset.PopulateParallel(4); err != nil {
+		t.Fatalf("LPRC.PopulateParallel() error = %v", err)
+	}
+	if got, want := multiset.Len(), uint64(4*minBlockSize); got != want {
+		t.Errorf("LPRC.Len() = %d, want %d", got, want)
+	}
+	if got, err := multiset.FullPrefixSearch(words[0]); err != nil || len(got) < 2 {
+		t.Errorf("LPRC.FullPrefixSearch(%q) = %v, %v, want the duplicates", words[0], got, err)
+	}
+}
+
+func TestSplitBlocks(t *testing.T) {
+	tests := []struct {
+		n       uint64
+		workers int
+		want    []uint64
+	}{
+		{0, 4, []uint64{0, 0}},
+		{minBlockSize, 4, []uint64{0, minBlockSize}},
+		{3 * minBlockSize, 2, []uint64{0, 1 + 3*minBlockSize/2, 3 * minBlockSize}},
+		{3*minBlockSize + 1, 3, []uint64{0, 1 + minBlockSize, 1 + 2*minBlockSize, 3*minBlockSize + 1}},
+		{3*minBlockSize + 2, 3, []uint64{0, 1 + minBlockSize + 32, 1 + 2*(minBlockSize+32), 3*minBlockSize + 2}},
+	}
+	for _, tt := range tests {
+		if got := splitBlocks(tt.n, tt.workers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitBlocks(%d, %d) = %v, want %v", tt.n, tt.workers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package stringcoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
+)
+
+// formatVersion is the version of the binary format written by WriteTo.
+// It must be increased each time the format changes.
+const formatVersion = uint32(9)
+
+// identifiers of the IntCoders in the header.
+const (
+	eliasGammaCoderID = uint32(iota + 1)
+	eliasDeltaCoderID
+	riceCoderID
+	fixedWidthCoderID
+	nibbleCoderID
+)
+
+// flags in the header.
+const (
+	multisetFlag = uint32(1 << iota)
+	eliasFanoStartsFlag
+	autoLengthsCoderFlag
+)
+
+var (
+	lprcMagic = [4]byte{'L', 'P', 'R', 'C'}
+	psrcMagic = [4]byte{'P', 'S', 'R', 'C'}
+)
+
+// header is the first part of a structure written by WriteTo.
+// It is followed by the BitData of the structure: with eliasFanoStartsFlag,
+// the low and the high bits of the starts take the place of Starts.
+// A LPRC writes then the strings inserted with Insert and not coded yet,
+// followed by the number of its segments and by each segment, written as a LPRC.
+type header struct {
+	Magic                      [4]byte
+	Version                    uint32
+	Epsilon                    float64
+	StringsCount               uint64
+	LatestCompressedBitWritten uint64
+	LengthsCoder               uint32
+	LengthsCoderParameter      uint32
+	Flags                      uint32
+	DeltaThreshold             uint64
+	CompactThreshold           float64
+}
+
+// WriteTo writes a populated LPRC to w, so that it can be loaded with ReadFrom
+// without populating it again. The strings inserted with Insert are written as they are,
+// so the ids of the coded strings do not change.
+// It returns ErrNotPopulated if the LPRC has not been populated yet.
+// It implements the io.WriterTo interface.
+func (lprc *LPRC) WriteTo(w io.Writer) (int64, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return lprc.writeTo(w)
+}
+
+// writeTo is WriteTo without locking.
+func (lprc *LPRC) writeTo(w io.Writer) (int64, error) {
+	if lprc.strings != nil {
+		return 0, ErrNotPopulated
+	}
+	coderID, coderParameter, err := getCoderID(lprc.coding.coder)
+	if err != nil {
+		return 0, err
+	}
+	h := header{lprcMagic, formatVersion, lprc.Epsilon, lprc.stringsCount, lprc.latestCompressedBitWritten,
+		coderID, coderParameter, getFlags(lprc.options, lprc.coding), uint64(lprc.options.deltaThreshold),
+		lprc.options.compactThreshold}
+	n, err := writeStructure(w, h, append(lprc.coding.bitDatas(), lprc.isUncompressed, lprc.deleted)...)
+	if err != nil {
+		return n, err
+	}
+	written, err := writeStrings(w, lprc.delta)
+	if n += written; err != nil {
+		return n, err
+	}
+	cw := &countingWriter{w: w}
+	if err := binary.Write(cw, binary.LittleEndian, uint64(len(lprc.segments))); err != nil {
+		return n + cw.n, err
+	}
+	for _, segment := range lprc.segments {
+		if _, err := segment.writeTo(cw); err != nil {
+			return n + cw.n, err
+		}
+	}
+	return n + cw.n, nil
+}
+
+// ReadFrom replaces the content of the LPRC with the one read from r,
+// as written by WriteTo. The loaded LPRC is ready to be queried,
+// with the optional parameters it had, such as the thresholds.
+// The zero LPRC can be loaded too, as long as it is not shared by other goroutines yet.
+// It implements the io.ReaderFrom interface.
+func (lprc *LPRC) ReadFrom(r io.Reader) (int64, error) {
+	loaded, n, err := readLPRC(r)
+	if err != nil {
+		return n, err
+	}
+	if lprc.guard == nil { // the zero LPRC
+		lprc.guard = newGuard()
+	}
+	lprc.guard.lock()
+	defer lprc.guard.unlock()
+	lprc.replace(loaded)
+	return n, nil
+}
+
+// readLPRC reads from r a LPRC written by WriteTo, together with its segments.
+func readLPRC(r io.Reader) (*LPRC, int64, error) {
+	h, n, err := readHeader(r, lprcMagic)
+	if err != nil {
+		return nil, n, err
+	}
+	var (
+		coding         = newLoadedCoding(h)
+		isUncompressed = &bd.BitData{}
+		deleted        = &bd.BitData{}
+	)
+	read, err := readBitDatas(r, append(coding.bitDatas(), isUncompressed, deleted)...)
+	if n += read; err != nil {
+		return nil, n, err
+	}
+	delta, read, err := readStrings(r)
+	if n += read; err != nil {
+		return nil, n, err
+	}
+	if !sort.StringsAreSorted(delta) {
+		return nil, n, ErrInvalidFormat
+	}
+	if err := prepareLoadedCoding(h, coding, isUncompressed); err != nil {
+		return nil, n, err
+	}
+	deletedCount, err := prepareLoadedDeleted(h, deleted)
+	if err != nil {
+		return nil, n, err
+	}
+	loaded := &LPRC{
+		coding:                     coding,
+		Epsilon:                    h.Epsilon,
+		c:                          2.0 + 2.0/h.Epsilon,
+		latestCompressedBitWritten: h.LatestCompressedBitWritten,
+		stringsCount:               h.StringsCount,
+		isUncompressed:             isUncompressed,
+		deleted:                    deleted,
+		deletedCount:               deletedCount,
+		delta:                      delta,
+		options:                    getLoadedOptions(h, coding),
+		guard:                      newGuard(),
+	}
+	if err := loaded.buildIndexes(); err != nil {
+		return nil, n, err
+	}
+	var segmentsCount uint64
+	if err := binary.Read(r, binary.LittleEndian, &segmentsCount); err != nil {
+		return nil, n, err
+	}
+	n += 8
+	for i := uint64(0); i < segmentsCount; i++ {
+		segment, read, err := readLPRC(r)
+		if n += read; err != nil {
+			return nil, n, err
+		}
+		if len(segment.delta) > 0 || len(segment.segments) > 0 ||
+			segment.options.multiset != loaded.options.multiset {
+			return nil, n, ErrInvalidFormat
+		}
+		loaded.segments = append(loaded.segments, segment)
+	}
+	return loaded, n, nil
+}
+
+// WriteTo writes a populated PSRC to w, so that it can be loaded with ReadFrom
+// without populating it again.
+// It returns ErrNotPopulated if the PSRC has not been populated yet.
+// It implements the io.WriterTo interface.
+func (psrc *PSRC) WriteTo(w io.Writer) (int64, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	if psrc.strings != nil {
+		return 0, ErrNotPopulated
+	}
+	coderID, coderParameter, err := getCoderID(psrc.coding.coder)
+	if err != nil {
+		return 0, err
+	}
+	h := header{psrcMagic, formatVersion, psrc.Epsilon, psrc.stringsCount, psrc.latestCompressedBitWritten,
+		coderID, coderParameter, getFlags(psrc.options, psrc.coding), uint64(psrc.options.deltaThreshold),
+		psrc.options.compactThreshold}
+	return writeStructure(w, h, append(psrc.coding.bitDatas(), psrc.isUncompressed, psrc.isStoredSuffix,
+		psrc.deleted)...)
+}
+
+// ReadFrom replaces the content of the PSRC with the one read from r,
+// as written by WriteTo. The loaded PSRC is ready to be queried and to append strings,
+// with the optional parameters it had, such as the thresholds.
+// The zero PSRC can be loaded too, as long as it is not shared by other goroutines yet.
+// It implements the io.ReaderFrom interface.
+func (psrc *PSRC) ReadFrom(r io.Reader) (int64, error) {
+	h, n, err := readHeader(r, psrcMagic)
+	if err != nil {
+		return n, err
+	}
+	var (
+		coding         = newLoadedCoding(h)
+		isUncompressed = &bd.BitData{}
+		isStoredSuffix = &bd.BitData{}
+		deleted        = &bd.BitData{}
+	)
+	read, err := readBitDatas(r, append(coding.bitDatas(), isUncompressed, isStoredSuffix, deleted)...)
+	if n += read; err != nil {
+		return n, err
+	}
+	if err := prepareLoadedCoding(h, coding, isUncompressed); err != nil {
+		return n, err
+	}
+	if isStoredSuffix.Len != h.StringsCount {
+		return n, ErrInvalidFormat
+	}
+	deletedCount, err := prepareLoadedDeleted(h, deleted)
+	if err != nil {
+		return n, err
+	}
+	loaded := PSRC{
+		coding:                     coding,
+		Epsilon:                    h.Epsilon,
+		c:                          2.0 + 2.0/h.Epsilon,
+		latestCompressedBitWritten: h.LatestCompressedBitWritten,
+		stringsCount:               h.StringsCount,
+		isUncompressed:             isUncompressed,
+		isStoredSuffix:             isStoredSuffix,
+		deleted:                    deleted,
+		deletedCount:               deletedCount,
+		options:                    getLoadedOptions(h, coding),
+	}
+	if err := loaded.buildIndexes(); err != nil {
+		return n, err
+	}
+	if h.StringsCount > 0 { // Append codes the next string from the last one, which is not written
+		if coding.LastString, err = loaded.decode(h.StringsCount - 1); err != nil {
+			return n, ErrInvalidFormat
+		}
+	}
+	if psrc.guard == nil { // the zero PSRC
+		psrc.guard = newGuard()
+	}
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	psrc.replace(&loaded)
+	return n, nil
+}
+
+// writeStructure writes the header h followed by each BitData to w.
+func writeStructure(w io.Writer, h header, bitDatas ...*bd.BitData) (int64, error) {
+	cw := &countingWriter{w: w}
+	if err := binary.Write(cw, binary.LittleEndian, h); err != nil {
+		return cw.n, err
+	}
+	for _, bitData := range bitDatas {
+		if _, err := bitData.WriteTo(cw); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// writeStrings writes to w the number of strings followed by each string,
+// as its length in bytes followed by its bytes.
+func writeStrings(w io.Writer, strings []string) (int64, error) {
+	cw := &countingWriter{w: w}
+	if err := binary.Write(cw, binary.LittleEndian, uint64(len(strings))); err != nil {
+		return cw.n, err
+	}
+	for _, s := range strings {
+		if err := binary.Write(cw, binary.LittleEndian, uint64(len(s))); err != nil {
+			return cw.n, err
+		}
+		if _, err := io.WriteString(cw, s); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// readStrings reads from r the strings written by writeStrings.
+// The memory taken grows with the data actually read, whatever lengths are read.
+func readStrings(r io.Reader) ([]string, int64, error) {
+	var count uint64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, 0, err
+	}
+	var (
+		strings []string
+		n       = int64(8)
+	)
+	for i := uint64(0); i < count; i++ {
+		var length uint64
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, n, err
+		}
+		n += 8
+		if length > math.MaxInt64 {
+			return nil, n, ErrInvalidFormat
+		}
+		var buffer bytes.Buffer
+		read, err := io.CopyN(&buffer, r, int64(length))
+		if n += read; err != nil {
+			return nil, n, err
+		}
+		strings = append(strings, buffer.String())
+	}
+	return strings, n, nil
+}
+
+// readHeader reads from r an header having the given magic number.
+func readHeader(r io.Reader, magic [4]byte) (header, int64, error) {
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return h, 0, err
+	}
+	n := int64(binary.Size(h))
+	if h.Magic != magic {
+		return h, n, ErrInvalidFormat
+	}
+	if h.Version != formatVersion {
+		return h, n, ErrUnsupportedVersion
+	}
+	if h.Epsilon <= float64(0) || h.Flags&^(multisetFlag|eliasFanoStartsFlag|autoLengthsCoderFlag) != 0 {
+		return h, n, ErrInvalidFormat
+	}
+	if h.DeltaThreshold > uint64(^uint(0)>>1) || !(h.CompactThreshold >= 0 && h.CompactThreshold <= 1) {
+		return h, n, ErrInvalidFormat
+	}
+	return h, n, nil
+}
+
+// readBitDatas reads each BitData from r.
+func readBitDatas(r io.Reader, bitDatas ...*bd.BitData) (int64, error) {
+	var n int64
+	for _, bitData := range bitDatas {
+		read, err := bitData.ReadFrom(r)
+		n += read
+		if err == bd.ErrInvalidFormat {
+			return n, ErrInvalidFormat
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// newLoadedCoding returns an empty Coding having the BitData written
+// by WriteTo for a structure having the header h.
+func newLoadedCoding(h header) *Coding {
+	coding := &Coding{Strings: &bd.BitData{}, Lengths: &bd.BitData{}}
+	if h.Flags&eliasFanoStartsFlag != 0 {
+		coding.startsEF = &eliasFano{low: &bd.BitData{}, high: &bd.BitData{}}
+	} else {
+		coding.Starts = &bd.BitData{}
+	}
+	return coding
+}
+
+// prepareLoadedCoding checks that the loaded data structures are consistent
+// with each other and rebuilds what is not written by WriteTo.
+func prepareLoadedCoding(h header, coding *Coding, isUncompressed *bd.BitData) error {
+	if isUncompressed.Len != h.StringsCount {
+		return ErrInvalidFormat
+	}
+	if err := prepareLoadedStarts(h, coding); err != nil {
+		return err
+	}
+	coder, err := getCoder(h.LengthsCoder, h.LengthsCoderParameter)
+	if err != nil {
+		return err
+	}
+	coding.coder = coder
+	if h.Flags&autoLengthsCoderFlag != 0 {
+		if coding.fitter, err = getFitter(coder); err != nil {
+			return err
+		}
+	}
+	coding.NextLengthsIndex = coding.Lengths.Len
+	if err := coding.indexLengths(); err != nil {
+		return ErrInvalidFormat
+	}
+	if h.StringsCount > 0 && coding.lengthsCount != h.StringsCount-1 { // the first string has no code
+		return ErrInvalidFormat
+	}
+	return nil
+}
+
+// prepareLoadedStarts checks that the loaded starts of the strings are in Strings.
+func prepareLoadedStarts(h header, coding *Coding) error {
+	ef := coding.startsEF
+	if ef == nil {
+		if coding.Starts.Len != coding.Strings.Len {
+			return ErrInvalidFormat
+		}
+		return nil
+	}
+	if err := ef.load(h.StringsCount); err != nil {
+		return err
+	}
+	if ef.count > 0 {
+		if last, err := ef.get(ef.count - 1); err != nil || last >= coding.Strings.Len {
+			return ErrInvalidFormat
+		}
+	}
+	return nil
+}
+
+// prepareLoadedDeleted checks the loaded marks of the deleted strings
+// and returns the number of deleted strings.
+func prepareLoadedDeleted(h header, deleted *bd.BitData) (uint64, error) {
+	if deleted.Len != h.StringsCount {
+		return uint64(0), ErrInvalidFormat
+	}
+	return countDeleted(deleted)
+}
+
+// getFlags returns the flags written in the header for the options o and the coding.
+func getFlags(o options, coding *Coding) uint32 {
+	flags := uint32(0)
+	if o.multiset {
+		flags |= multisetFlag
+	}
+	if coding.startsEF != nil { // it is not built until the strings are populated
+		flags |= eliasFanoStartsFlag
+	}
+	if coding.fitter != nil { // the coder has been chosen by an automatic IntCoder
+		flags |= autoLengthsCoderFlag
+	}
+	return flags
+}
+
+// getLoadedOptions returns the options of a structure having the header h and the loaded coding.
+// With an automatic IntCoder, the coder is chosen again when the strings are coded again.
+func getLoadedOptions(h header, coding *Coding) options {
+	coder := coding.coder
+	if auto, ok := coding.fitter.(IntCoder); ok {
+		coder = auto
+	}
+	return getOptions([]Option{WithLengthsCoder(coder), WithMultiset(h.Flags&multisetFlag != 0),
+		WithEliasFanoStarts(coding.startsEF != nil), WithDeltaThreshold(int(h.DeltaThreshold)),
+		WithCompactThreshold(h.CompactThreshold)})
+}
+
+// getCoderID returns the identifier and the parameter written in the header for coder.
+// Only the IntCoders of this package can be written: an automatic IntCoder is written
+// as the IntCoder it has chosen, together with autoLengthsCoderFlag.
+func getCoderID(coder IntCoder) (uint32, uint32, error) {
+	switch coder := coder.(type) {
+	case EliasGammaCoder:
+		return eliasGammaCoderID, 0, nil
+	case EliasDeltaCoder:
+		return eliasDeltaCoderID, 0, nil
+	case RiceCoder:
+		return riceCoderID, uint32(coder.K), nil
+	case FixedWidthCoder:
+		return fixedWidthCoderID, uint32(coder.Width), nil
+	case NibbleCoder:
+		return nibbleCoderID, 0, nil
+	}
+	return 0, 0, ErrUnsupportedCoder
+}
+
+// getCoder returns the IntCoder having the identifier and the parameter read from the header.
+func getCoder(id uint32, parameter uint32) (IntCoder, error) {
+	switch id {
+	case eliasGammaCoderID:
+		return EliasGammaCoder{}, nil
+	case eliasDeltaCoderID:
+		return EliasDeltaCoder{}, nil
+	case riceCoderID:
+		if parameter < 64 {
+			return RiceCoder{uint(parameter)}, nil
+		}
+	case fixedWidthCoderID:
+		if parameter > 0 && parameter <= 64 {
+			return FixedWidthCoder{uint(parameter)}, nil
+		}
+	case nibbleCoderID:
+		return NibbleCoder{}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+// getFitter returns the automatic IntCoder that chooses IntCoders of the same kind of coder.
+func getFitter(coder IntCoder) (fitter, error) {
+	switch coder.(type) {
+	case RiceCoder:
+		return AutoRiceCoder().(autoCoder), nil
+	case FixedWidthCoder:
+		return AutoFixedWidthCoder().(autoCoder), nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+// countingWriter is an io.Writer that counts the bytes written on the underlying io.Writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,253 @@
+package stringcoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLPRC_WriteToReadFrom(t *testing.T) {
+	var (
+		words  = randomWords(500, "abcd", 3)
+		lprc   = newLPRC(t, append([]string{}, words...), 1, WithDeltaThreshold(7), WithCompactThreshold(0.3))
+		buffer bytes.Buffer
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	written, err := lprc.WriteTo(&buffer)
+	if err != nil {
+		t.Fatalf("LPRC.WriteTo() error = %v", err)
+	}
+	if written != int64(buffer.Len()) {
+		t.Errorf("LPRC.WriteTo() = %d, but %d bytes were written", written, buffer.Len())
+	}
+
+	var loaded LPRC
+	read, err := loaded.ReadFrom(&buffer)
+	if err != nil {
+		t.Fatalf("LPRC.ReadFrom() error = %v", err)
+	}
+	if read != written {
+		t.Errorf("LPRC.ReadFrom() = %d, want %d", read, written)
+	}
+	if loaded.Epsilon != lprc.Epsilon {
+		t.Errorf("LPRC.ReadFrom() Epsilon = %v, want %v", loaded.Epsilon, lprc.Epsilon)
+	}
+	if loaded.options.deltaThreshold != 7 || loaded.options.compactThreshold != 0.3 {
+		t.Errorf("LPRC.ReadFrom() thresholds = %d, %v, want 7, 0.3", loaded.options.deltaThreshold,
+			loaded.options.compactThreshold)
+	}
+	for _, prefix := range []string{"", "a", "ab", "dcb", "e"} {
+		got, err := loaded.FullPrefixSearch(prefix)
+		if err != nil {
+			t.Errorf("LPRC.FullPrefixSearch(%q) error = %v", prefix, err)
+			continue
+		}
+		if want := filterPrefix(words, prefix); !reflect.DeepEqual(got, want) {
+			t.Errorf("LPRC.FullPrefixSearch(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+	for u := uint64(0); u < uint64(len(words)); u += 50 {
+		want, _ := lprc.Retrieval(u, 16)
+		if got, err := loaded.Retrieval(u, 16); err != nil || got != want {
+			t.Errorf("LPRC.Retrieval(%d, 16) = %v, %v, want %v", u, got, err, want)
+		}
+	}
+}
+
+func TestPSRC_WriteToReadFrom(t *testing.T) {
+	var (
+		words  = []string{"caso", "cat", "cena", "delfino"}
+		psrc   = newPSRC(t, append([]string{}, words...), 1, WithCompactThreshold(0.4))
+		buffer bytes.Buffer
+	)
+	if err := psrc.Populate(); err != nil {
+		t.Fatalf("PSRC.Populate() error = %v", err)
+	}
+	if _, err := psrc.WriteTo(&buffer); err != nil {
+		t.Fatalf("PSRC.WriteTo() error = %v", err)
+	}
+
+	var loaded PSRC
+	if _, err := loaded.ReadFrom(&buffer); err != nil {
+		t.Fatalf("PSRC.ReadFrom() error = %v", err)
+	}
+	if loaded.options.compactThreshold != 0.4 {
+		t.Errorf("PSRC.ReadFrom() compact threshold = %v, want 0.4", loaded.options.compactThreshold)
+	}
+	for _, prefix := range []string{"ca", "ce", "de", "no"} {
+		want, _ := psrc.FullPrefixSearch(prefix)
+		got, err := loaded.FullPrefixSearch(prefix)
+		if err != nil {
+			t.Errorf("PSRC.FullPrefixSearch(%q) error = %v", prefix, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PSRC.FullPrefixSearch(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
+
+func TestPSRC_ReadFromAppend(t *testing.T) {
+	words := randomWords(300, "abcd", 76)
+	for _, multiset := range []bool{false, true} {
+		var (
+			psrc     = newPSRC(t, append([]string{}, words...), 1, WithMultiset(multiset))
+			appended = []string{"bcdz", "zz", "az"}
+			buffer   bytes.Buffer
+			loaded   PSRC
+		)
+		if multiset {
+			appended = append(appended, words[len(words)-1], "zz")
+		}
+		if err := psrc.Populate(); err != nil {
+			t.Fatalf("PSRC.Populate() error = %v", err)
+		}
+		if _, err := psrc.WriteTo(&buffer); err != nil {
+			t.Fatalf("PSRC.WriteTo() error = %v", err)
+		}
+		if _, err := loaded.ReadFrom(&buffer); err != nil {
+			t.Fatalf("PSRC.ReadFrom() error = %v", err)
+		}
+		for _, s := range appended {
+			if _, err := psrc.Append(s); err != nil {
+				t.Fatalf("multiset %v: PSRC.Append(%q) error = %v", multiset, s, err)
+			}
+			id, err := loaded.Append(s)
+			if err != nil {
+				t.Fatalf("multiset %v: PSRC.Append(%q) after ReadFrom error = %v", multiset, s, err)
+			}
+			if got, err := loaded.Get(id); err != nil || got != s {
+				t.Errorf("multiset %v: PSRC.Get(%d) = %q, %v, want %q", multiset, id, got, err, s)
+			}
+		}
+		for _, prefix := range []string{"", "b", "z"} {
+			want, _ := psrc.FullPrefixSearch(prefix)
+			if got, err := loaded.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("multiset %v: PSRC.FullPrefixSearch(%q) = %d strings, %v, want %d", multiset, prefix,
+					len(got), err, len(want))
+			}
+		}
+	}
+}
+
+func TestWriteToReadFromAutoCoders(t *testing.T) {
+	var (
+		words  = randomWords(300, "abcd", 79)
+		longer = []string{strings.Repeat("a", 100), strings.Repeat("a", 99) + "b", "b" + strings.Repeat("a", 99)}
+	)
+	for _, coder := range []IntCoder{AutoRiceCoder(), AutoFixedWidthCoder()} {
+		var (
+			lprc       = newLPRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder), WithDeltaThreshold(0))
+			psrc       = newPSRC(t, append([]string{}, words...), 1, WithLengthsCoder(coder))
+			loadedLPRC LPRC
+			loadedPSRC PSRC
+		)
+		for _, pair := range []struct {
+			name              string
+			structure, loaded PrefixSearch
+		}{{"LPRC", &lprc, &loadedLPRC}, {"PSRC", &psrc, &loadedPSRC}} {
+			var buffer bytes.Buffer
+			if err := pair.structure.Populate(); err != nil {
+				t.Fatalf("%s: %s.Populate() error = %v", coder.Name(), pair.name, err)
+			}
+			if _, err := pair.structure.WriteTo(&buffer); err != nil {
+				t.Fatalf("%s: %s.WriteTo() error = %v", coder.Name(), pair.name, err)
+			}
+			if _, err := pair.loaded.ReadFrom(&buffer); err != nil {
+				t.Fatalf("%s: %s.ReadFrom() error = %v", coder.Name(), pair.name, err)
+			}
+		}
+		for name, codings := range map[string][2]*Coding{"LPRC": {lprc.coding, loadedLPRC.coding},
+			"PSRC": {psrc.coding, loadedPSRC.coding}} {
+			if want, got := codings[0], codings[1]; got.coder != want.coder || got.fitter == nil {
+				t.Errorf("%s: %s.ReadFrom() coder = %v, %v, want %v chosen automatically", coder.Name(), name,
+					got.coder, got.fitter, want.coder)
+			}
+		}
+		for _, s := range longer {
+			if err := loadedLPRC.Insert(s); err != nil { // coded at once, choosing the coder again
+				t.Errorf("%s: LPRC.Insert(%q) after ReadFrom error = %v", coder.Name(), s, err)
+			}
+			id, err := loadedPSRC.Append(s)
+			if err != nil {
+				t.Errorf("%s: PSRC.Append(%q) after ReadFrom error = %v", coder.Name(), s, err)
+			} else if got, err := loadedPSRC.Get(id); err != nil || got != s {
+				t.Errorf("%s: PSRC.Get(%d) = %q, %v, want %q", coder.Name(), id, got, err, s)
+			}
+		}
+		if got, err := loadedLPRC.FullPrefixSearch(longer[0][:20]); err != nil || !reflect.DeepEqual(got, longer[:2]) {
+			t.Errorf("%s: LPRC.FullPrefixSearch() after Insert = %v, %v", coder.Name(), got, err)
+		}
+	}
+}
+
+func TestReadFromInvalidData(t *testing.T) {
+	var (
+		lprc   = newLPRC(t, []string{"caso", "cat"}, 1)
+		psrc   PSRC
+		buffer bytes.Buffer
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	lprc.WriteTo(&buffer)
+	data := buffer.Bytes()
+
+	if _, err := psrc.ReadFrom(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Errorf("PSRC.ReadFrom() on a LPRC error = %v, want %v", err, ErrInvalidFormat)
+	}
+	data[4]++ // version
+	if _, err := lprc.ReadFrom(bytes.NewReader(data)); err != ErrUnsupportedVersion {
+		t.Errorf("LPRC.ReadFrom() error = %v, want %v", err, ErrUnsupportedVersion)
+	}
+	data[4]--
+	compactThreshold := data[binary.Size(header{})-8 : binary.Size(header{})]
+	binary.LittleEndian.PutUint64(compactThreshold, math.Float64bits(1.5))
+	if _, err := lprc.ReadFrom(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Errorf("LPRC.ReadFrom() with a compact threshold above 1 error = %v, want %v", err, ErrInvalidFormat)
+	}
+	binary.LittleEndian.PutUint64(compactThreshold, math.Float64bits(defaultCompactThreshold))
+	if _, err := lprc.ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Errorf("LPRC.ReadFrom() on truncated data should return an error")
+	}
+	stringsLength := data[binary.Size(header{}):] // the length of Strings follows the header
+	stringsLength[7] = 0xff
+	if _, err := lprc.ReadFrom(bytes.NewReader(data)); err != ErrInvalidFormat {
+		t.Errorf("LPRC.ReadFrom() with a corrupted length error = %v, want %v", err, ErrInvalidFormat)
+	}
+}
+
+func TestWriteToReadFromEliasFano(t *testing.T) {
+	words := randomWords(800, "abcdef", 75)
+	for name, eliasFano := range populatedImpls(t, words, WithEliasFanoStarts(true)) {
+		var (
+			buffer bytes.Buffer
+			loaded PrefixSearch
+		)
+		if _, err := eliasFano.WriteTo(&buffer); err != nil {
+			t.Fatalf("%s.WriteTo() error = %v", name, err)
+		}
+		if name == "LPRC" {
+			loaded = &LPRC{}
+		} else {
+			loaded = &PSRC{}
+		}
+		if _, err := loaded.ReadFrom(&buffer); err != nil {
+			t.Fatalf("%s.ReadFrom() error = %v", name, err)
+		}
+		if got, want := loaded.GetBitDataSize(), eliasFano.GetBitDataSize(); got["StartsSize"] != want["StartsSize"] {
+			t.Errorf("%s.ReadFrom() sizes = %v, want %v", name, got, want)
+		}
+		for _, prefix := range []string{"", "a", "fe"} {
+			want, _ := eliasFano.FullPrefixSearch(prefix)
+			if got, err := loaded.FullPrefixSearch(prefix); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s.FullPrefixSearch(%q) = %d strings, %v, want %d", name, prefix, len(got), err, len(want))
+			}
+		}
+	}
+}
@@ -1,11 +1,41 @@
 package stringcoding
 
-// PrefixSearch interface contains all the methods in order to run both LPRC and PSRC
+import (
+	"context"
+	"io"
+)
+
+// PrefixSearch interface contains all the methods in order to run both LPRC and PSRC.
+// The methods of a LPRC or a PSRC can be called by many goroutines at the same time:
+// the ones only reading the structure, such as FullPrefixSearch, run concurrently,
+// while the ones changing it, such as Populate, Insert or Append, run one at a time.
 type PrefixSearch interface {
 	Populate() error
+	PopulateContext(ctx context.Context) error
 	add(string, uint64) error
 	Retrieval(uint64, uint64) (string, error)
+	Get(id uint64) (string, error)
 	FullPrefixSearch(prefix string) ([]string, error)
+	FullPrefixSearchN(prefix string, offset int, limit int) ([]string, error)
+	FullPrefixSearchContext(ctx context.Context, prefix string) ([]string, error)
+	PrefixIterator(prefix string) Iterator
+	PrefixIteratorContext(ctx context.Context, prefix string) Iterator
+	CountPrefix(prefix string) (uint64, error)
 	GetBitDataSize() map[string]uint64
+	LengthsCoderName() string
+	StartsEncoding() string
+	WriteTo(io.Writer) (int64, error)
+	ReadFrom(io.Reader) (int64, error)
 	checkInterface()
 }
+
+// Iterator returns the results of a search one after the other.
+// An Iterator must be used by a single goroutine; if the structure is changed
+// while iterating, Next returns false and Err returns ErrConcurrentModification.
+type Iterator interface {
+	// Next returns the next string, or false if there are no more strings
+	// or an error occurred.
+	Next() (string, bool)
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
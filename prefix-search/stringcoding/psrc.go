@@ -1,9 +1,12 @@
 package stringcoding
 
 import (
+	"context"
 	"fmt"
 	bd "github.com/dariodip/prefix-search/prefix-search/bitdata"
-	"github.com/golang-collections/go-datastructures/bitarray"
+	"hash/fnv"
+	"io"
+	"strings"
 )
 
 // PSRCBitDataSize contains the size of all the data structures for PSRC
@@ -22,41 +25,235 @@ type PSRC struct {
 	c                          float64
 	latestCompressedBitWritten uint64
 	strings                    []string
+	stringsCount               uint64
 	isUncompressed             *bd.BitData
 	isStoredSuffix             *bd.BitData
+	// deleted has a bit set to 1 for each string deleted with Delete or DeleteID.
+	deleted      *bd.BitData
+	deletedCount uint64
+	options      options
+	// hashes maps the hash of each string, deleted ones included, to the ids of the
+	// strings having it, so that Append looks for a duplicate decoding only them.
+	// It is built by the first Append without WithMultiset.
+	hashes map[uint64][]uint64
+	guard  *guard
 }
 
 // NewPSRC return an implementation of PSRC: a storage method
 // based on RC (Rear Coding) that stores a string s in an
 // uncompressed way if the latest c|s| bits do not contain
 // an uncompressed string.
-func NewPSRC(strings []string, epsilon float64) PSRC {
-	stringsCount := uint64(len(strings))
-	if epsilon <= float64(0) { // check if epsilon is valid
-		panic("epsilon should be greater than 0")
+// The optional parameters, such as the IntCoder for Lengths, are given by opts.
+// It returns ErrInvalidEpsilon if epsilon is not greater than 0 and an *OptionError
+// if an optional parameter is not valid.
+func NewPSRC(strings []string, epsilon float64, opts ...Option) (PSRC, error) {
+	o := getOptions(opts)
+	if err := o.validate(epsilon); err != nil {
+		return PSRC{}, err
 	}
+	stringsCount := uint64(len(strings))
 	c := 2.0 + 2.0/epsilon
-	return PSRC{New(strings),
+	return PSRC{NewWithCoder(strings, o.lengthsCoder),
 		epsilon,
 		c, 0,
 		strings,
-		bd.New(bitarray.NewBitArray(stringsCount), stringsCount),
-		bd.New(bitarray.NewBitArray(stringsCount), stringsCount)}
+		stringsCount,
+		bd.New(stringsCount, 0),
+		bd.New(stringsCount, 0),
+		bd.New(stringsCount, stringsCount),
+		0,
+		o,
+		nil,
+		newGuard()}, nil
 }
 
-// Populate populates all the trie
+// Populate populates all the trie.
+// Once populated, the structure does not keep any reference to the input strings.
+// Without WithMultiset, it returns ErrEmptyString or ErrDuplicateString, before coding
+// any string, if the strings contain the empty string or the same string twice.
+// If a string cannot be coded, it returns a *StringError and the structure is left
+// as it was before, so that it can be populated again.
 func (psrc *PSRC) Populate() error {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	return psrc.populate()
+}
+
+// populate is Populate without locking.
+func (psrc *PSRC) populate() error {
+	return psrc.populateContext(context.Background())
+}
+
+// populateContext is PopulateContext without locking.
+func (psrc *PSRC) populateContext(ctx context.Context) error {
+	if !psrc.options.multiset {
+		if err := checkStrings(psrc.strings); err != nil {
+			return err
+		}
+	}
 	for i, s := range psrc.strings {
+		if err := canceled(ctx); err != nil {
+			psrc.unpopulate()
+			return err
+		}
 		if err := psrc.add(s, uint64(i)); err != nil {
+			psrc.unpopulate()
+			return &StringError{uint64(i), err}
+		}
+	}
+	psrc.strings = nil // let the input strings be garbage collected
+	if err := psrc.coding.fitLengthsCoder(); err != nil {
+		return err
+	}
+	if psrc.options.eliasFanoStarts {
+		if err := psrc.coding.encodeStartsWithEliasFano(); err != nil {
+			return err
+		}
+	}
+	psrc.coding.shrink()
+	return psrc.buildIndexes()
+}
+
+// buildIndexes builds the rank/select directories on the BitData
+// that are queried by Retrieval.
+func (psrc *PSRC) buildIndexes() error {
+	if err := psrc.coding.buildStartsIndex(); err != nil {
+		return err
+	}
+	return psrc.isUncompressed.BuildIndex()
+}
+
+// Append adds the string s after the strings already in the PSRC and returns its id,
+// that is the index u of the string to use in Retrieval.
+// The strings given to NewPSRC are populated first, if it has not been done yet.
+// The queries run after Append see the appended string.
+// Without WithMultiset, it returns ErrEmptyString for the empty string and ErrDuplicateString
+// if s is already in the PSRC: the check keeps the hashes of the strings, which the first
+// Append computes decoding the PSRC, so that it decodes only the strings having the hash of s.
+func (psrc *PSRC) Append(s string) (uint64, error) {
+	psrc.guard.lock()
+	defer psrc.guard.unlock()
+	multiset := psrc.options.multiset
+	if len(s) == 0 && !multiset {
+		return uint64(0), ErrEmptyString
+	}
+	if err := psrc.populateIfNeeded(); err != nil {
+		return uint64(0), err
+	}
+	if !multiset {
+		if found, err := psrc.contains(s); err != nil || found {
+			if err == nil {
+				err = ErrDuplicateString
+			}
+			return uint64(0), err
+		}
+	}
+	var (
+		coding = psrc.coding
+		id     = psrc.stringsCount
+	)
+	if err := psrc.add(s, id); err != nil { // the BitData grow to make room for the string
+		return uint64(0), &StringError{id, err}
+	}
+	if err := psrc.deleted.AppendBit(false); err != nil {
+		return uint64(0), err
+	}
+	psrc.stringsCount++
+	if psrc.hashes != nil {
+		h := stringHash(s)
+		psrc.hashes[h] = append(psrc.hashes[h], id)
+	}
+	if !coding.hasStartsIndex() || !psrc.isUncompressed.HasIndex() { // they are kept up to date by add
+		if err := psrc.buildIndexes(); err != nil {
+			return uint64(0), err
+		}
+	}
+	return id, nil
+}
+
+// checkStrings returns an error if the strings contain the empty string or the same string twice.
+func checkStrings(strings []string) error {
+	seen := make(map[string]bool, len(strings))
+	for _, s := range strings {
+		if len(s) == 0 {
+			return ErrEmptyString
+		}
+		if seen[s] {
+			return ErrDuplicateString
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+// contains tells if the string s is in the PSRC, deleted strings excluded.
+// Only the strings having the same hash as s are decoded, once hashes is built.
+func (psrc *PSRC) contains(s string) (bool, error) {
+	if psrc.hashes == nil {
+		if err := psrc.buildHashes(); err != nil {
+			return false, err
+		}
+	}
+	for _, id := range psrc.hashes[stringHash(s)] {
+		isDeleted, err := psrc.deleted.GetBit(id)
+		if err != nil {
+			return false, err
+		}
+		if isDeleted {
+			continue
+		}
+		stringI, err := psrc.get(id)
+		if err != nil {
+			return false, err
+		}
+		if stringI == s {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// buildHashes builds hashes decoding each string once.
+func (psrc *PSRC) buildHashes() error {
+	hashes := make(map[uint64][]uint64, psrc.stringsCount)
+	if psrc.stringsCount > 0 {
+		cursor, err := psrc.newCursor()
+		if err != nil {
 			return err
 		}
+		for {
+			s, err := cursor.String()
+			if err != nil {
+				return err
+			}
+			h := stringHash(s)
+			hashes[h] = append(hashes[h], cursor.index)
+			if cursor.index+1 == psrc.stringsCount {
+				break
+			}
+			if err := cursor.next(); err != nil {
+				return err
+			}
+		}
 	}
+	psrc.hashes = hashes
 	return nil
 }
 
+// stringHash returns the hash of s kept in hashes.
+func stringHash(s string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, s)
+	return h.Sum64()
+}
+
 func (psrc *PSRC) add(s string, index uint64) error {
 	coding := psrc.coding // extracting our coding data structure
 
+	if index != psrc.isUncompressed.Len { // strings are added one after the other
+		return bd.ErrIndexOutOfBound
+	}
+
 	s = string("\x00") + s + string("\x00")
 	bdS, errGbd := bd.GetBitData(s) // 1: convert string s to a bitdata bdS
 	if errGbd != nil {
@@ -93,45 +290,43 @@ func (psrc *PSRC) add(s string, index uint64) error {
 
 	saveUncompressed := saveUncompressedPSRC(stringToAdd, bdS, psrc) // should our string be saved uncompressed?
 	if saveUncompressed {                                            // we have to save our string uncompressed
-		stringToAdd = bdS                                         // so the string to save is the full string
-		psrc.latestCompressedBitWritten = uint64(0)               // compressed bit written is now 0
-		if err := psrc.isUncompressed.SetBit(index); err != nil { // We can compress s
-			return err
-		}
-	}
-	errAppendBit := coding.Strings.AppendBits(stringToAdd) // 3: append string to Strings bitdata
-	if errAppendBit != nil {
-		panic(errAppendBit) // we don't know if the method has written in the structure
-		// so we have to stop all the process and redo... sorry :(
+		stringToAdd = bdS // so the string to save is the full string
 	}
 
-	// 4: li is the number of bit to remove on the prefix (risp. suffix) in the preceding string
+	// 3: li is the number of bit to remove on the prefix (risp. suffix) in the preceding string.
+	// It is the first thing we write, so nothing is written if it cannot be encoded
 	li = bdS.Len - stringToAdd.Len // our string - different suffix
 	if coding.LastString != nil {
-		errAppUL := coding.encodeEliasGamma(calcLen(li, coding.LastString.Len))
-		if errAppUL != nil { // as above...
-			panic(errAppUL)
+		if err := coding.encodeLength(calcLen(li, coding.LastString.Len)); err != nil {
+			return err
 		}
 	}
 
+	errAppendBit := coding.Strings.AppendBits(stringToAdd) // 4: append string to Strings bitdata
+	if errAppendBit != nil {
+		return errAppendBit
+	}
+
 	errSetSWO := coding.setStartsWithOffset(stringToAdd) // 5: set the bit of the next string in the Starts array
 	if errSetSWO != nil {
-		panic(errSetSWO)
+		return errSetSWO
 	}
 	coding.LastString = bdS // 6: update last string
-	if !saveUncompressed {  // 7: if the string was saved compressed we have to update latestCompressedBitWritten counter
+	if saveUncompressed {   // 7: update latestCompressedBitWritten counter
+		psrc.latestCompressedBitWritten = uint64(0) // compressed bit written is now 0
+	} else {
 		psrc.latestCompressedBitWritten += stringToAdd.Len
 	}
-	if storeSuffix {
-		psrc.isStoredSuffix.SetBit(index)
-	} else {
-		psrc.isStoredSuffix.ClearBit(index) // just for safety
+	if err := psrc.isUncompressed.AppendBit(saveUncompressed); err != nil { // 8: how has the string been stored?
+		return err
 	}
-	return nil
+	return psrc.isStoredSuffix.AppendBit(storeSuffix)
 }
 
+// saveUncompressedPSRC tells if the string bdS, whose different suffix or prefix is stringToAdd, must be
+// saved uncompressed. A duplicate has no different part, so it is saved uncompressed to have its own bits.
 func saveUncompressedPSRC(stringToAdd *bd.BitData, bdS *bd.BitData, psrc *PSRC) bool {
-	return stringToAdd.Len == bdS.Len || float64(psrc.latestCompressedBitWritten) > psrc.c*float64(bdS.Len)
+	return stringToAdd.Len == bdS.Len || stringToAdd.Len == 0 || float64(psrc.latestCompressedBitWritten) > psrc.c*float64(bdS.Len)
 }
 
 func (psrc *PSRC) getStringLength(i uint64) (uint64, error) {
@@ -146,8 +341,8 @@ func (psrc *PSRC) getStringLength(i uint64) (uint64, error) {
 	if err != nil {
 		return uint64(0), err
 	}
-	li, err := psrc.coding.decodeIthEliasGamma(i) // li is the number of bits to remove in string(p(i)) in order to
-	if err != nil {                               // obtain the prefix for string(i)
+	li, err := psrc.coding.decodeIthLength(i) // li is the number of bits to remove in string(p(i)) in order to
+	if err != nil {                           // obtain the prefix for string(i)
 		return uint64(0), err
 	}
 	ni := lengthStringPI - li
@@ -160,7 +355,13 @@ func (psrc *PSRC) getStringLength(i uint64) (uint64, error) {
 
 // Retrieval (u, l) returns the prefix of the string string(u) with length l.
 // So the returned prefix ends up in the edge (p(u), u).
+// If string(u) has been deleted, it returns ErrDeletedString.
 func (psrc *PSRC) Retrieval(u uint64, l uint64) (string, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	if err := checkDeleted(psrc.deleted, u); err != nil {
+		return "", err
+	}
 	l += 8
 	var (
 		stringBuffer *bd.BitData
@@ -178,126 +379,17 @@ func (psrc *PSRC) Retrieval(u uint64, l uint64) (string, error) {
 				l = ll // we can only return a string as big as our string
 			}
 		} // end else
-		stringBuffer := bd.New(bitarray.NewBitArray(l), l)
-		err = psrc.populateBuffer(stringBuffer, l, u, uint64(0), l) // we get the first l bits of that string
+		stringBuffer, err := psrc.storedPrefix(u, l) // we get the first l bits of that string
 		if err != nil {
-			panic(err)
+			return "", &StringError{u, err}
 		}
 		return stringBuffer.BitToTrimmedString()
 	} else { // our string is stored compressed
-		// we'll do Select1(V, Rank1(V, u))
-		v, err := psrc.isUncompressed.Rank1(u) // extract the number of 1s before u in isUncompressed
-		if err != nil {                        // i.e. the number of uncompressed strings before u
-			return "", err
-		}
-		vPosition, err := psrc.isUncompressed.Select1(v) // extract the position of the v-th string
-		if err != nil {                                  // i.e. the first uncompressed string before u
-			return "", err
-		}
-		vStarts, err := psrc.coding.Starts.Select1(vPosition + 1) // give me the position where the string v starts in Strings
-		if err != nil {                                           // where v is the first uncompressed string before u
-			return "", err
-		}
-		vNextStarts, err := psrc.coding.Starts.Select1(vPosition + 1 + 1) // give me the position of the string next to v
-		if err != nil {                                                   // in order to extract the size of string(v)
+		var err error
+		if stringBuffer, err = psrc.decodeCompressed(u); err != nil {
 			return "", err
 		}
-		lengthStringV := vNextStarts - vStarts // that's the length of string(v)
-		stringBuffer = bd.New(bitarray.NewBitArray(lengthStringV), lengthStringV)
-		err = psrc.populateBuffer(stringBuffer, lengthStringV, vPosition, 0, lengthStringV) // insert the first l bits of string(v) in the buffer
-		if err != nil {
-			panic(err)
-		}
-		for i := vPosition + 1; i <= u; i++ { // for each string i between v and u (we follow the path on the trie in dfs order)
-			li, err := psrc.coding.decodeIthEliasGamma(i) // li is the number of bits to remove in string(p(i)) in order to
-			if err != nil {                               // obtain the common string for string(i)
-				return "", err
-			}
-			ni := lengthStringV - li // this is the length of the common bits between string(p(i))
-			// and string(i)
-			lengthI, err := psrc.getLengthInStrings(i) // length of the suffix of string(i) in Strings
-			lengthStringV = lengthI + ni               // total length of string(i)
-			if err != nil {
-				return "", err
-			}
-			isStoredSuffix, err := psrc.isStoredSuffix.GetBit(u)
-			if err != nil {
-				return "", err
-			}
-			newBuffer := bd.New(bitarray.NewBitArray(lengthStringV), lengthStringV)
-			if isStoredSuffix {
-				// we didn't store LastString.Len - li but li
-				sbLen := stringBuffer.Len
-				for i := uint64(0); i < ni; i++ {
-					bit, err := stringBuffer.GetBit(sbLen - 1 - i)
-					if err != nil {
-						return "", err
-					}
-					if bit {
-						newBuffer.SetBit(newBuffer.Len - 1 - i)
-					}
-				}
-
-				uPosition, err := psrc.coding.Starts.Select1(i + 1) // We need to now where the next string starts
-				if err != nil {
-					return "", err
-				}
-				lengthI, err := psrc.getLengthInStrings(i)
-				if err != nil {
-					return "", err
-				}
-
-				for i := uint64(0); i < lengthI; i++ {
-					bit, err := psrc.coding.Strings.GetBit(uPosition + i)
-					if err != nil {
-						return "", err
-					}
-					if bit {
-						newBuffer.SetBit(i)
-					}
-				}
-				stringBuffer = newBuffer
-			} else {
-
-				var uPosition uint64
-				if (u + 1) == uint64(len(psrc.strings)) {
-					uPosition = psrc.coding.Strings.Len // u is the last string memorized!
-				} else {
-					var err error
-					uPosition, err = psrc.coding.Starts.Select1(u + 1 + 1) // We need to now where the next string starts
-					if err != nil {
-						return "", err
-					}
-				}
-				uPosition -= 1
-				for i := uint64(0); i < lengthI; i++ {
-					bit, err := psrc.coding.Strings.GetBit(uPosition - i)
-					if err != nil {
-						return "", err
-					}
-					if bit {
-						err := newBuffer.SetBit(newBuffer.Len - 1 - i)
-						if err != nil {
-							return "", err
-						}
-					}
-				}
-				for i := uint64(0); i < ni; i++ {
-					bit, err := stringBuffer.GetBit(i)
-					if err != nil {
-						return "", err
-					}
-					if bit {
-						err := newBuffer.SetBit(i)
-						if err != nil {
-							return "", err
-						}
-					}
-				}
-				stringBuffer = newBuffer
-			} // end else !isStoredSuffix
-		} // end for
-	} //end else !isUncompressedStringU
+	}
 
 	if stringBuffer.Len < l { // Our string is too short
 		return "", ErrTooShortString
@@ -309,16 +401,153 @@ func (psrc *PSRC) Retrieval(u uint64, l uint64) (string, error) {
 	return firstLBits.BitToTrimmedString()
 }
 
+// Get returns the string having the given id, as returned by Append, or its index
+// among the strings given to NewPSRC. Deleted strings keep their ids.
+// If the id does not identify any string, it returns an *IDOutOfRangeError, while
+// if the string has been deleted, it returns ErrDeletedString.
+func (psrc *PSRC) Get(id uint64) (string, error) {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return psrc.get(id)
+}
+
+// get is Get without locking.
+func (psrc *PSRC) get(id uint64) (string, error) {
+	if id >= psrc.stringsCount {
+		return "", &IDOutOfRangeError{id, psrc.stringsCount}
+	}
+	if err := checkDeleted(psrc.deleted, id); err != nil {
+		return "", err
+	}
+	if psrc.strings != nil { // the PSRC has not been populated yet
+		return psrc.strings[id], nil
+	}
+	stringBuffer, err := psrc.decode(id)
+	if err != nil {
+		return "", err
+	}
+	s, err := stringBuffer.BitToString()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(s, "\x00"), "\x00"), nil // drop the null chars around the string
+}
+
+// decode returns the string u, together with the null chars around it,
+// whether it has been deleted or not.
+func (psrc *PSRC) decode(u uint64) (*bd.BitData, error) {
+	isUncompressed, err := psrc.isUncompressed.GetBit(u)
+	if err != nil {
+		return nil, err
+	}
+	if !isUncompressed {
+		return psrc.decodeCompressed(u)
+	}
+	length, err := psrc.getLengthInStrings(u)
+	if err != nil {
+		return nil, err
+	}
+	return psrc.storedPrefix(u, length)
+}
+
+// decodeCompressed returns the string u, which is stored compressed, together with
+// the null chars around it. It is decoded from the uncompressed string before u.
+func (psrc *PSRC) decodeCompressed(u uint64) (*bd.BitData, error) {
+	var (
+		stringBuffer *bd.BitData
+	)
+	// we'll do Select1(V, Rank1(V, u))
+	v, err := psrc.isUncompressed.Rank1(u) // extract the number of 1s before u in isUncompressed
+	if err != nil {                        // i.e. the number of uncompressed strings before u
+		return nil, err
+	}
+	vPosition, err := psrc.isUncompressed.Select1(v) // extract the position of the v-th string
+	if err != nil {                                  // i.e. the first uncompressed string before u
+		return nil, err
+	}
+	vStarts, err := psrc.coding.start(vPosition) // give me the position where the string v starts in Strings
+	if err != nil {                              // where v is the first uncompressed string before u
+		return nil, err
+	}
+	vNextStarts, err := psrc.coding.start(vPosition + 1) // give me the position of the string next to v
+	if err != nil {                                      // in order to extract the size of string(v)
+		return nil, err
+	}
+	lengthStringV := vNextStarts - vStarts                          // that's the length of string(v)
+	stringBuffer, err = psrc.storedPrefix(vPosition, lengthStringV) // insert the first l bits of string(v) in the buffer
+	if err != nil {
+		return nil, &StringError{vPosition, err}
+	}
+	lengths, err := psrc.coding.newLengthsReader(vPosition + 1) // we decode the codes of the strings after v one after the other
+	if err != nil {
+		return nil, err
+	}
+	for i := vPosition + 1; i <= u; i++ { // for each string i between v and u (we follow the path on the trie in dfs order)
+		li, err := lengths.next() // li is the number of bits to remove in string(p(i)) in order to
+		if err != nil {           // obtain the common string for string(i)
+			return nil, err
+		}
+		if stringBuffer, err = psrc.decodeNext(stringBuffer, i, li); err != nil {
+			return nil, err
+		}
+	} // end for
+	return stringBuffer, nil
+}
+
+// decodeNext returns the string i, together with the null chars around it, given the
+// previous string stringBuffer and li, the code of the string i in Lengths.
+// The bits are copied a word at a time.
+func (psrc *PSRC) decodeNext(stringBuffer *bd.BitData, i uint64, li uint64) (*bd.BitData, error) {
+	if li > stringBuffer.Len {
+		return nil, bd.ErrIndexOutOfBound
+	}
+	ni := stringBuffer.Len - li // this is the length of the common bits between string(p(i))
+	// and string(i)
+	lengthI, err := psrc.getLengthInStrings(i) // length of the suffix of string(i) in Strings
+	if err != nil {
+		return nil, err
+	}
+	uPosition, err := psrc.coding.start(i) // where the bits of string(i) start in Strings
+	if err != nil {
+		return nil, err
+	}
+	isStoredSuffix, err := psrc.isStoredSuffix.GetBit(i)
+	if err != nil {
+		return nil, err
+	}
+	newBuffer := bd.New(lengthI+ni, 0)
+	if isStoredSuffix {
+		// the suffix stored in Strings is made of the least significant bits, while
+		// the common prefix is made of the most significant bits of the previous string
+		if err := newBuffer.AppendRange(psrc.coding.Strings, uPosition, lengthI); err != nil {
+			return nil, err
+		}
+		if err := newBuffer.AppendRange(stringBuffer, stringBuffer.Len-ni, ni); err != nil {
+			return nil, err
+		}
+		return newBuffer, nil
+	}
+	// the prefix stored in Strings is made of the most significant bits, while
+	// the common suffix is made of the least significant bits of the previous string
+	if err := newBuffer.AppendRange(stringBuffer, 0, ni); err != nil {
+		return nil, err
+	}
+	if err := newBuffer.AppendRange(psrc.coding.Strings, uPosition, lengthI); err != nil {
+		return nil, err
+	}
+	return newBuffer, nil
+}
+
 func (psrc *PSRC) getLengthInStrings(i uint64) (uint64, error) {
-	startPositionI, err := psrc.coding.Starts.Select1(i + 1)
+	startPositionI, err := psrc.coding.start(i)
 	if err != nil {
 		return uint64(0), err
 	}
 	var startPositionSuccI uint64
-	if (i + 1) == uint64(len(psrc.strings)) {
-		startPositionSuccI = psrc.coding.Starts.Len // u is the last string memorized!
+	if (i + 1) == psrc.stringsCount {
+		startPositionSuccI = psrc.coding.Strings.Len // u is the last string memorized!
 	} else {
-		startPositionSuccI, err = psrc.coding.Starts.Select1(i + 1 + 1) // We need to now where the next string starts
+		startPositionSuccI, err = psrc.coding.start(i + 1) // We need to now where the next string starts
 		if err != nil {
 			return uint64(0), err
 		}
@@ -327,43 +556,27 @@ func (psrc *PSRC) getLengthInStrings(i uint64) (uint64, error) {
 	return startPositionSuccI - startPositionI, nil
 }
 
-func (psrc *PSRC) populateBuffer(stringBuffer *bd.BitData, l uint64, u uint64, ni uint64, vlen uint64) error {
-	var (
-		uPosition uint64
-		maxIt     uint64
-	)
-	if (u + 1) == uint64(len(psrc.strings)) {
+// storedPrefix returns the l most significant bits stored in Strings for the string u,
+// that are its first l bits if it is stored uncompressed. They are copied a word at a time.
+func (psrc *PSRC) storedPrefix(u uint64, l uint64) (*bd.BitData, error) {
+	var uPosition uint64
+	if (u + 1) == psrc.stringsCount {
 		uPosition = psrc.coding.Strings.Len // u is the last string memorized!
 	} else {
 		var err error
-		uPosition, err = psrc.coding.Starts.Select1(u + 1 + 1) // We need to now where the next string starts
+		uPosition, err = psrc.coding.start(u + 1) // We need to now where the next string starts
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
-	if vlen < l {
-		maxIt = vlen
-	} else {
-		maxIt = l - ni
-	}
-	uPosition = uPosition - 1            // The most significant bits are at the end
-	for i := uint64(0); i < maxIt; i++ { // let's iterate for i = 0 up to l - 1 (l times)
-		// We start from the most significant bits
-		lastBit, lastBitErr := psrc.coding.Strings.GetBit(uPosition - i) // take the i-th bit of string(u)
-		if lastBitErr != nil {                                           // getBit has gone wrong
-			return lastBitErr
-		}
-
-		// The last ((l - 1) - ni) bits in stringBuffer are the number of most significant bit in common between
-		// the two consecutive strings
-		indexToUpdate := ((l - 1) - ni) - i
-		if lastBit {
-			stringBuffer.SetBit(indexToUpdate)
-		} else {
-			stringBuffer.ClearBit(indexToUpdate)
-		}
+	if l > uPosition { // The most significant bits are at the end
+		return nil, bd.ErrIndexOutOfBound
 	}
-	return nil
+	prefix := bd.New(l, 0)
+	if err := prefix.AppendRange(psrc.coding.Strings, uPosition-l, l); err != nil {
+		return nil, err
+	}
+	return prefix, nil
 }
 
 func (psrc *PSRC) checkInterface() {
@@ -371,62 +584,61 @@ func (psrc *PSRC) checkInterface() {
 		return true
 	}
 	var sPs PrefixSearch
-	psrcImpl := NewPSRC([]string{}, 1.0)
+	psrcImpl, _ := NewPSRC([]string{}, 1.0)
 	sPs = &psrcImpl
 	checkFunc(sPs)
 }
 
 func (psrc *PSRC) String() string {
-	return fmt.Sprintf(`type:%T coding:%v, Epsilon:%v, c:%v, strings:%v, isUncompressed:%v, isStoredSuffix:%v`,
-		psrc, psrc.coding, psrc.Epsilon, psrc.c, psrc.strings, psrc.isUncompressed, psrc.isStoredSuffix)
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return fmt.Sprintf(`type:%T coding:%v, Epsilon:%v, c:%v, stringsCount:%v, isUncompressed:%v, isStoredSuffix:%v`,
+		psrc, psrc.coding, psrc.Epsilon, psrc.c, psrc.stringsCount, psrc.isUncompressed, psrc.isStoredSuffix)
 }
 
 // FullPrefixSearch , given a prefix *prefix* returns all the strings that start with that prefix.
+// The strings are returned in the order they have been added.
 func (psrc *PSRC) FullPrefixSearch(prefix string) ([]string, error) {
-	var (
-		lenPrefix    = uint64(len(prefix) * 8) // |prefix|
-		totalStrings = uint64(len(psrc.strings))
-		stringBuffer = []string{}
-		prefixBuffer = []uint64{}
-	)
-
-	for i := uint64(0); i < totalStrings; i++ {
-		retrievalI, err := psrc.Retrieval(i, lenPrefix)
-		if err != nil && err != ErrTooShortString { // If the string is too short, then we simply skip it
-			return nil, err // if error was found
-		}
-		if retrievalI == prefix { // we found the first node having
-			prefixBuffer = append(prefixBuffer, i)
-		}
-	}
-	if len(prefixBuffer) == 0 {
-		return []string{}, nil
-	}
-
-	for _, index := range prefixBuffer {
-		stringLength, err := psrc.getStringLength(index)
-		if err != nil {
-			return []string{}, err
-		}
-		stringLength -= 8
-		prefixedString, err := psrc.Retrieval(index, stringLength)
-		if err != nil {
-			return []string{}, err
-		}
-		stringBuffer = append(stringBuffer, prefixedString)
-	}
+	return psrc.FullPrefixSearchN(prefix, 0, -1)
+}
 
-	return stringBuffer, nil
+// Len returns the number of strings in the PSRC that have not been deleted.
+func (psrc *PSRC) Len() uint64 {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return psrc.stringsCount - psrc.deletedCount
 }
 
-// GetBitDataSize returns the size in bits of the BitData used to compress the strings
+// GetBitDataSize returns the size in bits of the memory taken by the BitData used to compress
+// the strings, including their unused capacity.
 func (psrc *PSRC) GetBitDataSize() map[string]uint64 {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
 	sizes := make(map[string]uint64)
-	sizes["StringSize"] = psrc.coding.Strings.Len
-	sizes["StartsSize"] = psrc.coding.Starts.Len
-	sizes["LenghtsSize"] = psrc.coding.Lengths.Len
-	sizes["IsUncompressedSize"] = psrc.isUncompressed.Len
-	sizes["PrefixOrSuffixSize"] = psrc.isStoredSuffix.Len
+	sizes["StringSize"] = dataSize(psrc.coding.Strings)
+	startsSize, startsIndexSize := psrc.coding.startsSize()
+	sizes["StartsSize"] = startsSize
+	sizes["LenghtsSize"] = dataSize(psrc.coding.Lengths)
+	sizes["IsUncompressedSize"] = dataSize(psrc.isUncompressed)
+	sizes["RankSelectSize"] = startsIndexSize + psrc.isUncompressed.IndexSize()
+	sizes["PrefixOrSuffixSize"] = dataSize(psrc.isStoredSuffix)
+	sizes["TombstonesSize"] = dataSize(psrc.deleted)
 
 	return sizes
 }
+
+// LengthsCoderName returns the name of the IntCoder used for Lengths, which is
+// the one it has chosen once the strings are populated with an automatic IntCoder.
+func (psrc *PSRC) LengthsCoderName() string {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return psrc.coding.coder.Name()
+}
+
+// StartsEncoding returns the name of the representation of the starts of the strings,
+// "EliasFano" once the strings are populated with WithEliasFanoStarts and "Bitvector" otherwise.
+func (psrc *PSRC) StartsEncoding() string {
+	psrc.guard.rlock()
+	defer psrc.guard.runlock()
+	return psrc.coding.startsEncoding()
+}
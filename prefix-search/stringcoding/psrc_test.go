@@ -2,6 +2,7 @@ package stringcoding
 
 import (
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -206,7 +207,7 @@ func TestPSRC_Retrieval(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			psrc := NewPSRC(tt.fields.strings, tt.fields.Epsilon)
+			psrc := newPSRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				psrc.add(s, uint64(i))
 			}
@@ -277,7 +278,7 @@ func TestPSRC_getStringLength(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			psrc := NewPSRC(tt.fields.strings, tt.fields.Epsilon)
+			psrc := newPSRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				psrc.add(s, uint64(i))
 			}
@@ -395,7 +396,7 @@ func TestPSRC_FullPrefixSearch(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			psrc := NewPSRC(tt.fields.strings, tt.fields.Epsilon)
+			psrc := newPSRC(t, tt.fields.strings, tt.fields.Epsilon)
 			for i, s := range tt.fields.strings {
 				psrc.add(s, uint64(i))
 			}
@@ -411,3 +412,58 @@ func TestPSRC_FullPrefixSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestPSRC_Append(t *testing.T) {
+	var (
+		words    = randomWords(600, "abcd", 7)
+		prefixes = append(randomWords(20, "abcd", 8), "")
+		psrc     = newPSRC(t, append([]string{}, words[:100]...), 1)
+	)
+	for i, s := range words[100:] {
+		id, err := psrc.Append(s)
+		if err != nil {
+			t.Fatalf("PSRC.Append(%q) error = %v", s, err)
+		}
+		if id != uint64(100+i) {
+			t.Errorf("PSRC.Append(%q) = %d, want %d", s, id, 100+i)
+		}
+		if got, err := psrc.Retrieval(id, uint64(len(s)*8)); err != nil || got != s {
+			t.Errorf("PSRC.Retrieval(%d) = %v, %v, want %v", id, got, err, s)
+		}
+		if i%100 != 0 {
+			continue
+		}
+		for _, prefix := range prefixes { // the queries between appends see all the strings added so far
+			got, err := psrc.FullPrefixSearch(prefix)
+			if err != nil {
+				t.Fatalf("PSRC.FullPrefixSearch(%q) error = %v", prefix, err)
+			}
+			sort.Strings(got)
+			if want := filterPrefix(words[:101+i], prefix); !reflect.DeepEqual(got, want) {
+				t.Errorf("PSRC.FullPrefixSearch(%q) = %v, want %v", prefix, got, want)
+			}
+		}
+	}
+	if !psrc.coding.Starts.HasIndex() || !psrc.isUncompressed.HasIndex() {
+		t.Errorf("PSRC.Append() should keep the rank/select directories")
+	}
+	if _, err := psrc.Append(""); err != ErrEmptyString {
+		t.Errorf("PSRC.Append(\"\") error = %v, want %v", err, ErrEmptyString)
+	}
+}
+
+func TestPSRC_AppendEmpty(t *testing.T) {
+	var (
+		words = []string{"delfino", "caso", "cena", "cat"}
+		psrc  = newPSRC(t, nil, 1, WithLengthsCoder(AutoRiceCoder()))
+	)
+	for i, s := range words {
+		if id, err := psrc.Append(s); err != nil || id != uint64(i) {
+			t.Fatalf("PSRC.Append(%q) = %d, %v, want %d", s, id, err, i)
+		}
+	}
+	got, err := psrc.FullPrefixSearch("ca")
+	if want := []string{"caso", "cat"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("PSRC.FullPrefixSearch() = %v, %v, want %v", got, err, want)
+	}
+}
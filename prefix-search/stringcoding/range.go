@@ -0,0 +1,125 @@
+package stringcoding
+
+import "sort"
+
+// RangeIterator returns an Iterator over the strings s of the LPRC such that lo <= s < hi,
+// in lexicographic order. The strings inserted with Insert are returned too.
+// Once the LPRC is changed, the iterator stops and its Err returns ErrConcurrentModification.
+func (lprc *LPRC) RangeIterator(lo string, hi string) Iterator {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return newSyncIterator(lprc.rangeIterator(lo, hi), lprc.guard)
+}
+
+// rangeIterator is RangeIterator without locking.
+func (lprc *LPRC) rangeIterator(lo string, hi string) Iterator {
+	if hi <= lo { // the range is empty
+		return &lprcIterator{}
+	}
+	span := func(part *LPRC) (uint64, uint64, error) {
+		from, err := part.lowerBound(lo)
+		if err != nil {
+			return uint64(0), uint64(0), err
+		}
+		to, err := part.lowerBound(hi)
+		return from, to, err
+	}
+	delta := lprc.delta[sort.SearchStrings(lprc.delta, lo):sort.SearchStrings(lprc.delta, hi)]
+	return lprc.newIterator(span, delta)
+}
+
+// Range returns all the strings s of the LPRC such that lo <= s < hi, in lexicographic order.
+func (lprc *LPRC) Range(lo string, hi string) ([]string, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	return collect(lprc.rangeIterator(lo, hi), 0, -1)
+}
+
+// Predecessor returns the greatest string of the LPRC that comes before s,
+// and false if there is no such string.
+func (lprc *LPRC) Predecessor(s string) (string, bool, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	var (
+		predecessor string
+		i           = sort.SearchStrings(lprc.delta, s)
+		found       = i > 0
+	)
+	if found {
+		predecessor = lprc.delta[i-1]
+	}
+	for _, part := range lprc.parts() {
+		u, err := part.lowerBound(s)
+		if err != nil {
+			return "", false, err
+		}
+		coded, ok, err := part.previousLive(u)
+		if err != nil {
+			return "", false, err
+		}
+		if ok && (!found || coded > predecessor) {
+			predecessor, found = coded, true
+		}
+	}
+	return predecessor, found, nil
+}
+
+// Successor returns the smallest string of the LPRC that comes after s,
+// and false if there is no such string.
+func (lprc *LPRC) Successor(s string) (string, bool, error) {
+	lprc.guard.rlock()
+	defer lprc.guard.runlock()
+	var (
+		after     = s + "\x00" // the strings equal to s come before it, the ones after s do not
+		successor string
+		i         = sort.SearchStrings(lprc.delta, after)
+		found     = i < len(lprc.delta)
+	)
+	if found {
+		successor = lprc.delta[i]
+	}
+	for _, part := range lprc.parts() {
+		u, err := part.lowerBound(after)
+		if err != nil {
+			return "", false, err
+		}
+		coded, ok, err := part.nextLive(u)
+		if err != nil {
+			return "", false, err
+		}
+		if ok && (!found || coded < successor) {
+			successor, found = coded, true
+		}
+	}
+	return successor, found, nil
+}
+
+// previousLive returns the last coded string before the u-th one that has not been deleted.
+func (lprc *LPRC) previousLive(u uint64) (string, bool, error) {
+	for ; u > 0; u-- {
+		isDeleted, err := lprc.deleted.GetBit(u - 1)
+		if err != nil {
+			return "", false, err
+		}
+		if !isDeleted {
+			s, err := lprc.getCoded(u - 1)
+			return s, err == nil, err
+		}
+	}
+	return "", false, nil
+}
+
+// nextLive returns the first coded string, starting from the u-th one, that has not been deleted.
+func (lprc *LPRC) nextLive(u uint64) (string, bool, error) {
+	for ; u < lprc.stringsCount; u++ {
+		isDeleted, err := lprc.deleted.GetBit(u)
+		if err != nil {
+			return "", false, err
+		}
+		if !isDeleted {
+			s, err := lprc.getCoded(u)
+			return s, err == nil, err
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,91 @@
+package stringcoding
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLPRC_Range(t *testing.T) {
+	var (
+		words  = randomWords(700, "abcd", 19)
+		bounds = append(randomWords(30, "abcde", 20), "", "a", "e")
+		lprc   = newLPRC(t, append([]string{}, words[:600]...), 1)
+		live   = []string{}
+	)
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for _, s := range words[600:] {
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for i, s := range words {
+		if i%4 == 0 {
+			if _, err := lprc.Delete(s); err != nil {
+				t.Fatalf("LPRC.Delete(%q) error = %v", s, err)
+			}
+		} else {
+			live = append(live, s)
+		}
+	}
+	sort.Strings(live)
+
+	for _, lo := range bounds {
+		for _, hi := range bounds[:10] {
+			want := []string{}
+			for _, s := range live {
+				if lo <= s && s < hi {
+					want = append(want, s)
+				}
+			}
+			if got, err := lprc.Range(lo, hi); err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("LPRC.Range(%q, %q) = %v, %v, want %v", lo, hi, got, err, want)
+			}
+		}
+	}
+	for _, s := range append(bounds, words...) {
+		var (
+			i        = sort.SearchStrings(live, s)
+			wantPred = ""
+			wantSucc = ""
+		)
+		if i > 0 {
+			wantPred = live[i-1]
+		}
+		if i < len(live) && live[i] == s {
+			i++
+		}
+		if i < len(live) {
+			wantSucc = live[i]
+		}
+		if got, found, err := lprc.Predecessor(s); err != nil || got != wantPred || found != (wantPred != "") {
+			t.Errorf("LPRC.Predecessor(%q) = %q, %v, %v, want %q", s, got, found, err, wantPred)
+		}
+		if got, found, err := lprc.Successor(s); err != nil || got != wantSucc || found != (wantSucc != "") {
+			t.Errorf("LPRC.Successor(%q) = %q, %v, %v, want %q", s, got, found, err, wantSucc)
+		}
+	}
+}
+
+func TestLPRC_SuccessorMultiset(t *testing.T) {
+	lprc := newLPRC(t, []string{"a", "b", "b", "b", "c"}, 1, WithMultiset(true), WithDeltaThreshold(10))
+	if err := lprc.Populate(); err != nil {
+		t.Fatalf("LPRC.Populate() error = %v", err)
+	}
+	for _, s := range []string{"b", "b", "d", "e", "e"} { // kept in the delta buffer
+		if err := lprc.Insert(s); err != nil {
+			t.Fatalf("LPRC.Insert(%q) error = %v", s, err)
+		}
+	}
+	for s, want := range map[string]string{"": "a", "a": "b", "b": "c", "ba": "c", "c": "d", "d": "e", "e": ""} {
+		if got, found, err := lprc.Successor(s); err != nil || got != want || found != (want != "") {
+			t.Errorf("LPRC.Successor(%q) = %q, %v, %v, want %q", s, got, found, err, want)
+		}
+	}
+	want := []string{"b", "b", "b", "b", "b", "c"}
+	if got, err := lprc.Range("b", "d"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LPRC.Range(\"b\", \"d\") = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,82 @@
+package stringcoding
+
+// parts returns the LPRC followed by its segments: the queries merge
+// the strings coded by each of them.
+func (lprc *LPRC) parts() []*LPRC {
+	return append([]*LPRC{lprc}, lprc.segments...)
+}
+
+// partOf returns the part of the LPRC coding the string having the given id, together
+// with the id of the string in the part: the ids of the strings coded by a segment
+// follow the ones of the strings coded before it.
+func (lprc *LPRC) partOf(id uint64) (*LPRC, uint64, error) {
+	u := id
+	for _, part := range lprc.parts() {
+		if u < part.stringsCount {
+			return part, u, nil
+		}
+		u -= part.stringsCount
+	}
+	count, _ := lprc.codedCount()
+	return nil, uint64(0), &IDOutOfRangeError{id, count}
+}
+
+// codedCount returns the number of strings coded by all the parts of the LPRC,
+// deleted ones included, and the number of deleted ones.
+func (lprc *LPRC) codedCount() (uint64, uint64) {
+	var count, deletedCount uint64
+	for _, part := range lprc.parts() {
+		count += part.stringsCount
+		deletedCount += part.deletedCount
+	}
+	return count, deletedCount
+}
+
+// newSegment returns a populated LPRC, having the options of lprc, coding the strings,
+// which must be sorted.
+func (lprc *LPRC) newSegment(strings []string) (*LPRC, error) {
+	segment, err := NewLPRC(strings, lprc.Epsilon, withOptions(lprc.options))
+	if err != nil {
+		return nil, err
+	}
+	if err := segment.populate(); err != nil {
+		return nil, err
+	}
+	return &segment, nil
+}
+
+// mergeSegments codes together the last two segments, without their deleted strings,
+// as long as the last one holds at least half the strings of the one before, so that
+// each segment holds more than twice the strings of the next one: this way there are
+// at most a logarithmic number of segments, and each string is coded again a logarithmic
+// number of times before Compact.
+func (lprc *LPRC) mergeSegments() error {
+	for n := len(lprc.segments); n > 1; n = len(lprc.segments) {
+		var (
+			previous = lprc.segments[n-2]
+			last     = lprc.segments[n-1]
+		)
+		if previous.stringsCount-previous.deletedCount > 2*(last.stringsCount-last.deletedCount) {
+			return nil
+		}
+		previousStrings, err := previous.codedPrefixSearch("") // deleted strings are skipped
+		if err != nil {
+			return err
+		}
+		lastStrings, err := last.codedPrefixSearch("")
+		if err != nil {
+			return err
+		}
+		merged := mergeSorted(previousStrings, lastStrings)
+		if len(merged) == 0 { // all their strings have been deleted
+			lprc.segments = lprc.segments[:n-2]
+			continue
+		}
+		segment, err := lprc.newSegment(merged)
+		if err != nil {
+			return err
+		}
+		lprc.segments = append(lprc.segments[:n-2], segment)
+	}
+	return nil
+}